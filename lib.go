@@ -23,6 +23,10 @@ import (
 	"sync"
 )
 
-func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory pkg.HandlerFactory) error {
-	return pkg.Start(ctx, wg, config, handlerfactory)
+func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory pkg.HandlerFactory, opts ...pkg.Option) error {
+	return pkg.Start(ctx, wg, config, handlerfactory, opts...)
+}
+
+func StartWithDependencies(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory pkg.DependencyHandlerFactory, opts ...pkg.Option) error {
+	return pkg.StartWithDependencies(ctx, wg, config, handlerfactory, opts...)
 }
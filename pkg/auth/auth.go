@@ -17,19 +17,96 @@
 package auth
 
 import (
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
-	"time"
+	"golang.org/x/sync/singleflight"
 )
 
+// Auth is safe for concurrent use; concurrent token fetches, refreshes and exchanges for the same user are deduplicated
 type Auth struct {
-	config configuration.Config
-	cache  *cache.Cache
-	openid *OpenidToken
+	config     configuration.Config
+	cache      *cache.Typed[string, Token]
+	mux        sync.Mutex
+	openid     *OpenidToken
+	flight     singleflight.Group
+	httpClient *http.Client
+	verifier   *Verifier
+	discovery  *discovery
+	source     TokenSource
+	usages     map[string]*usage //exchanged tokens tracked for the refresher; nil if the refresher is not running
 }
 
 func New(config configuration.Config) *Auth {
-	return &Auth{config: config, cache: cache.NewCache(config.TokenCacheDefaultExpirationInSeconds)}
+	discovery := newDiscovery(config)
+	source, err := NewTokenSource(config)
+	if err != nil {
+		config.GetLogger().Error("invalid auth token source config", "error", err)
+		source = errorSource{err: err}
+	}
+	backend, err := cache.NewBackend(config)
+	if err != nil {
+		config.GetLogger().Error("invalid cache backend config, user tokens are only cached in-process", "error", err)
+	}
+	return &Auth{
+		config: config,
+		cache: cache.NewTyped[string, Token](time.Duration(config.TokenCacheDefaultExpirationInSeconds)*time.Second,
+			cache.WithMaxEntries(config.TokenCacheMaxEntries),
+			cache.WithLogger(config.GetLogger()),
+			cache.WithBackend(backend, "auth/"+config.AuthClientId+"/")), // exchanged tokens are only shared between instances of the same client
+		httpClient: http.DefaultClient,
+		verifier:   newVerifier(config, discovery),
+		discovery:  discovery,
+		source:     source,
+	}
+}
+
+// CacheStats returns the counters of the user token cache
+func (this *Auth) CacheStats() cache.Stats {
+	return this.cache.Stats()
+}
+
+// SetTokenSource replaces the TokenSource selected by config.AuthTokenSource and drops the current client token
+func (this *Auth) SetTokenSource(source TokenSource) *Auth {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.source = source
+	this.openid = nil
+	return this
+}
+
+// Endpoints returns the token and jwks endpoints of the configured realm,
+// resolved by openid discovery (cached) or, if the discovery fails, derived from config.AuthEndpoint
+func (this *Auth) Endpoints() Endpoints {
+	return this.discovery.Get()
+}
+
+// Verify parses an inbound token with the Verifier of this Auth (sharing its jwks cache)
+func (this *Auth) Verify(token string) (Token, error) {
+	return this.verifier.Parse(token)
+}
+
+// parse is used for tokens received from the auth server;
+// the signature and claims are only checked if config.AuthVerifyTokens is set
+func (this *Auth) parse(token string) (Token, error) {
+	if this.config.AuthVerifyTokens {
+		return this.verifier.parse(token, false)
+	}
+	return Parse(token)
+}
+
+// SetHttpClient replaces the http.Client used for requests to the auth server;
+// discovery and jwks requests use a copy with a timeout of 10s, if client has no timeout
+func (this *Auth) SetHttpClient(client *http.Client) *Auth {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	this.httpClient = client
+	this.verifier.SetHttpClient(client)
+	return this
 }
 
 var TimeNow = func() time.Time {
@@ -0,0 +1,336 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/golang-jwt/jwt"
+)
+
+// newTestServer starts an authtest.Server for the client "client" with the secret "secret"; it is closed with the test
+func newTestServer(t *testing.T, options ...authtest.Option) *authtest.Server {
+	t.Helper()
+	server, err := authtest.NewServer("client", "secret", options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testConfig returns the config of an Auth for the client of server
+func testConfig(server *authtest.Server) configuration.Config {
+	return configuration.Config{AuthEndpoint: server.URL, AuthClientId: "client", AuthClientSecret: "secret", TokenCacheDefaultExpirationInSeconds: 60, LogLevel: "error"}
+}
+
+// newTestAuth returns an Auth using a new authtest.Server
+func newTestAuth(t *testing.T) (*Auth, *authtest.Server) {
+	t.Helper()
+	server := newTestServer(t)
+	return New(testConfig(server)), server
+}
+
+// run with -race
+func TestConcurrentEnsureAndExchange(t *testing.T) {
+	a, server := newTestAuth(t)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			token, err := a.Ensure()
+			if err != nil || token.GetUserId() != server.ClientSubject() {
+				t.Error(err, token)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			userId := "user" + strconv.Itoa(i%5)
+			token, err := a.ExchangeUserToken(userId)
+			if err != nil || token.GetUserId() != userId {
+				t.Error(err, token)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	counts := map[string]int{}
+	for _, request := range server.Requests() {
+		counts[request.GrantType+":"+request.RequestedSubject]++
+	}
+	if counts["client_credentials:"] != 1 {
+		t.Error(counts)
+	}
+	for i := 0; i < 5; i++ {
+		if counts[authtest.TokenExchangeGrantType+":user"+strconv.Itoa(i)] != 1 {
+			t.Error(counts)
+		}
+	}
+}
+
+// run with -race
+func TestConcurrentRefresh(t *testing.T) {
+	server := newTestServer(t)
+	server.SetExpiries(15*time.Second, time.Minute, time.Minute)
+
+	a := New(testConfig(server))
+	_, err := a.Ensure()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// let the access token expire
+	a.mux.Lock()
+	a.openid.RequestTime = a.openid.RequestTime.Add(-10 * time.Second)
+	a.mux.Unlock()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.Ensure()
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	counts := map[string]int{}
+	for _, request := range server.Requests() {
+		counts[request.GrantType]++
+	}
+	if counts["client_credentials"] != 1 || counts["refresh_token"] != 1 {
+		t.Error(counts)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := newTestServer(t)
+	config := testConfig(server)
+
+	t.Run("invalid client credentials", func(t *testing.T) {
+		c := config
+		c.AuthClientSecret = "wrong"
+		a := New(c)
+		_, err := a.Ensure()
+		if !errors.Is(err, ErrInvalidClientCredentials) || !errors.Is(err, ErrAuth) {
+			t.Error(err)
+		}
+		_, err = a.ExchangeUserToken("user")
+		if !errors.Is(err, ErrInvalidClientCredentials) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		c := config
+		c.AuthEndpoint = "http://localhost:1"
+		_, err := New(c).Ensure()
+		if !errors.Is(err, ErrAuthServerUnreachable) {
+			t.Error(err)
+		}
+		server.InjectError("client_credentials", http.StatusServiceUnavailable, 1)
+		_, err = New(config).Ensure()
+		if !errors.Is(err, ErrAuthServerUnreachable) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("exchange forbidden", func(t *testing.T) {
+		server.ForbidExchange("forbidden")
+		_, err := New(config).ExchangeUserToken("forbidden")
+		if !errors.Is(err, ErrExchangeForbidden) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("forbidden exchanges are remembered", func(t *testing.T) {
+		server.ForbidExchange("forbidden-cached")
+		a := New(config)
+		for i := 0; i < 3; i++ {
+			_, err := a.ExchangeUserToken("forbidden-cached")
+			if !errors.Is(err, ErrExchangeForbidden) {
+				t.Error(err)
+			}
+		}
+		count := 0
+		for _, request := range server.Requests() {
+			if request.RequestedSubject == "forbidden-cached" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Error(count)
+		}
+	})
+
+	t.Run("unavailable exchanges are remembered shortly", func(t *testing.T) {
+		c := config
+		c.AuthEndpoint = "http://localhost:1"
+		a := New(c)
+		for i := 0; i < 2; i++ {
+			_, err := a.ExchangeUserToken("user")
+			if !errors.Is(err, ErrAuthServerUnreachable) {
+				t.Error(err)
+			}
+		}
+		if stats := a.CacheStats(); stats.NegativeHits != 1 || stats.LoadErrors != 1 {
+			t.Error("unreachable auth server should be remembered", stats)
+		}
+
+		server.InjectError(authtest.TokenExchangeGrantType, http.StatusServiceUnavailable, 1)
+		a = New(config)
+		for i := 0; i < 2; i++ {
+			_, err := a.ExchangeUserToken("unavailable")
+			if !errors.Is(err, ErrAuthServerUnreachable) {
+				t.Error(err)
+			}
+		}
+		count := 0
+		for _, request := range server.Requests() {
+			if request.RequestedSubject == "unavailable" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Error("5xx responses should be remembered", count)
+		}
+	})
+
+	t.Run("recover after error", func(t *testing.T) {
+		a := New(config)
+		server.InjectError("client_credentials", http.StatusBadRequest, 1)
+		_, err := a.Ensure()
+		if !errors.Is(err, ErrAccessDenied) {
+			t.Error(err)
+			return
+		}
+		token, err := a.Ensure()
+		if err != nil || token.GetUserId() != server.ClientSubject() {
+			t.Error(err, token)
+		}
+	})
+}
+
+func TestExchangeUserTokenWith(t *testing.T) {
+	a, server := newTestAuth(t)
+
+	claims := func(token Token) jwt.MapClaims {
+		result := jwt.MapClaims{}
+		_, _, err := new(jwt.Parser).ParseUnverified(strings.TrimPrefix(token.Jwt(), "Bearer "), result)
+		if err != nil {
+			t.Error(err)
+		}
+		return result
+	}
+
+	plain, err := a.ExchangeUserToken("user")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	withAudience, err := a.ExchangeUserTokenWith("user", ExchangeOptions{Audience: "other-service", Scope: "openid email"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if plain.Jwt() == withAudience.Jwt() {
+		t.Error("options should be part of the cache key")
+		return
+	}
+	if aud := claims(withAudience)["aud"]; aud != "other-service" {
+		t.Error(aud)
+	}
+	if scope := claims(withAudience)["scope"]; scope != "openid email" {
+		t.Error(scope)
+	}
+	if aud := claims(plain)["aud"]; aud != "account" {
+		t.Error(aud)
+	}
+
+	cached, err := a.ExchangeUserTokenWith("user", ExchangeOptions{Scope: "openid email", Audience: "other-service"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cached.Jwt() != withAudience.Jwt() {
+		t.Error("expected cached token")
+	}
+
+	exchanges := 0
+	for _, request := range server.Requests() {
+		if request.GrantType == authtest.TokenExchangeGrantType {
+			exchanges++
+			if request.RequestedSubject != "user" {
+				t.Error(request)
+			}
+		}
+	}
+	if exchanges != 2 {
+		t.Error(exchanges)
+	}
+	last := server.Requests()[len(server.Requests())-1]
+	if last.Form["audience"][0] != "other-service" || last.Form["scope"][0] != "openid email" || len(last.Form["requested_token_type"]) != 0 {
+		t.Error(last.Form)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	a, _ := newTestAuth(t)
+
+	first, err := a.Ensure()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a.InvalidateToken(first)
+	second, err := a.Ensure()
+	if err != nil || second.Jwt() == first.Jwt() {
+		t.Error("expected new client token", err)
+		return
+	}
+	a.InvalidateToken(first) // outdated tokens are ignored
+	third, err := a.Ensure()
+	if err != nil || third.Jwt() != second.Jwt() {
+		t.Error("expected current client token", err)
+		return
+	}
+
+	userToken, err := a.ExchangeUserToken("user")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a.InvalidateUserToken("user")
+	renewed, err := a.ExchangeUserToken("user")
+	if err != nil || renewed.Jwt() == userToken.Jwt() {
+		t.Error("expected new user token", err)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package authmock provides an auth.Auth replacement for tests of clients retrying requests after 401 responses,
+// like smartservicerepository.SmartServiceRepository or the script env of the middleware.
+package authmock
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+)
+
+// RotatingAuth returns "token-<n>" as client and user token and increments n on every invalidation.
+// It implements the TokenInvalidator interfaces of smartservicerepository and scriptenv and is safe for concurrent use.
+type RotatingAuth struct {
+	mux           sync.Mutex
+	generation    int
+	invalidations []string
+}
+
+func (this *RotatingAuth) token(userid string) (auth.Token, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return auth.Token{Token: "token-" + strconv.Itoa(this.generation), Sub: userid}, nil
+}
+
+func (this *RotatingAuth) Ensure() (token auth.Token, err error) {
+	return this.token("")
+}
+
+func (this *RotatingAuth) ExchangeUserToken(userid string) (token auth.Token, err error) {
+	return this.token(userid)
+}
+
+func (this *RotatingAuth) InvalidateToken(token auth.Token) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.generation++
+	this.invalidations = append(this.invalidations, "client:"+token.Token)
+}
+
+func (this *RotatingAuth) InvalidateUserToken(userid string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.generation++
+	this.invalidations = append(this.invalidations, "user:"+userid)
+}
+
+// Invalidations returns the invalidations in order: "client:<token>" for InvalidateToken and "user:<userid>" for InvalidateUserToken
+func (this *RotatingAuth) Invalidations() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.invalidations)
+}
@@ -0,0 +1,393 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package authtest provides a local OpenID Connect token server for tests of auth.Auth.
+// Set configuration.Config.AuthEndpoint to Server.URL.
+// The server mints RS256 signed jwts for the client_credentials, refresh_token and token-exchange grants
+// and publishes the public key as jwks and the endpoints as .well-known/openid-configuration.
+package authtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// RealmPath is the default realm path (Keycloak < 17, realm master); see WithRealmPath
+const RealmPath = "/auth/realms/master"
+const TokenPath = "/protocol/openid-connect/token"
+const CertsPath = "/protocol/openid-connect/certs"
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+const TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+type Server struct {
+	URL string
+
+	server             *httptest.Server
+	realmPath          string
+	discovery          bool
+	mux                sync.Mutex
+	key                *rsa.PrivateKey
+	keyId              string
+	clientId           string
+	clientSecret       string
+	clientPublicKey    *rsa.PublicKey
+	now                func() time.Time
+	accessTokenExpiry  time.Duration
+	refreshTokenExpiry time.Duration
+	exchangeExpiry     time.Duration
+	roles              map[string][]string //subject -> realm roles
+	forbiddenExchanges map[string]bool
+	refreshTokens      map[string]session
+	injected           []*injectedError
+	requests           []Request
+	tokenCount         int
+}
+
+// Request is a received token request
+type Request struct {
+	GrantType        string
+	ClientId         string
+	RequestedSubject string
+	Form             map[string][]string
+}
+
+type session struct {
+	subject string
+	expires time.Time
+}
+
+type injectedError struct {
+	grantType  string
+	statusCode int
+	remaining  int
+}
+
+type Option func(*Server)
+
+// WithRealmPath serves the realm below path instead of RealmPath (e.g. "/realms/myrealm" for Keycloak >= 17)
+func WithRealmPath(path string) Option {
+	return func(server *Server) {
+		server.realmPath = path
+	}
+}
+
+// WithoutDiscovery disables the .well-known/openid-configuration endpoint
+func WithoutDiscovery() Option {
+	return func(server *Server) {
+		server.discovery = false
+	}
+}
+
+// NewServer starts a server that accepts the client credentials clientId and clientSecret
+func NewServer(clientId string, clientSecret string, options ...Option) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	result := &Server{
+		realmPath:          RealmPath,
+		discovery:          true,
+		key:                key,
+		keyId:              "authtest",
+		clientId:           clientId,
+		clientSecret:       clientSecret,
+		now:                time.Now,
+		accessTokenExpiry:  5 * time.Minute,
+		refreshTokenExpiry: 30 * time.Minute,
+		exchangeExpiry:     5 * time.Minute,
+		roles:              map[string][]string{},
+		forbiddenExchanges: map[string]bool{},
+		refreshTokens:      map[string]session{},
+	}
+	for _, option := range options {
+		option(result)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+result.realmPath+TokenPath, result.token)
+	mux.HandleFunc("GET "+result.realmPath+CertsPath, result.certs)
+	if result.discovery {
+		mux.HandleFunc("GET "+result.realmPath+DiscoveryPath, result.openidConfiguration)
+	}
+	result.server = httptest.NewServer(mux)
+	result.URL = result.server.URL
+	return result, nil
+}
+
+func (this *Server) Close() {
+	this.server.Close()
+}
+
+// Issuer returns the iss claim of minted tokens
+func (this *Server) Issuer() string {
+	return this.URL + this.realmPath
+}
+
+// ClientSubject is the sub claim of tokens minted for the client_credentials grant
+func (this *Server) ClientSubject() string {
+	return "service-account-" + this.clientId
+}
+
+func (this *Server) PublicKey() *rsa.PublicKey {
+	return &this.key.PublicKey
+}
+
+// SetNow replaces the clock used for iat, exp and refresh-token expiry
+func (this *Server) SetNow(now func() time.Time) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.now = now
+}
+
+// SetExpiries sets the lifetime of access tokens, refresh tokens and exchanged user tokens
+func (this *Server) SetExpiries(accessToken time.Duration, refreshToken time.Duration, exchangedToken time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.accessTokenExpiry = accessToken
+	this.refreshTokenExpiry = refreshToken
+	this.exchangeExpiry = exchangedToken
+}
+
+// SetRoles sets the realm roles of a subject (a user id or ClientSubject())
+func (this *Server) SetRoles(subject string, roles ...string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.roles[subject] = roles
+}
+
+// ForbidExchange lets token exchanges for the user fail with 403
+func (this *Server) ForbidExchange(userId string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.forbiddenExchanges[userId] = true
+}
+
+// InjectError lets the next count token requests with the grant type fail with statusCode; an empty grantType matches every grant
+func (this *Server) InjectError(grantType string, statusCode int, count int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.injected = append(this.injected, &injectedError{grantType: grantType, statusCode: statusCode, remaining: count})
+}
+
+// SetClientPublicKey additionally accepts private_key_jwt client assertions signed by the matching private key
+func (this *Server) SetClientPublicKey(key *rsa.PublicKey) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.clientPublicKey = key
+}
+
+// RevokeRefreshTokens invalidates all issued refresh tokens
+func (this *Server) RevokeRefreshTokens() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.refreshTokens = map[string]session{}
+}
+
+func (this *Server) Requests() []Request {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.requests)
+}
+
+// Mint returns a signed access token for the subject, independent of any grant
+func (this *Server) Mint(subject string, expiresIn time.Duration) (string, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.mint(subject, "Bearer", expiresIn, nil)
+}
+
+func (this *Server) token(writer http.ResponseWriter, request *http.Request) {
+	err := request.ParseForm()
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	grantType := request.PostForm.Get("grant_type")
+
+	this.mux.Lock()
+	defer this.mux.Unlock()
+
+	this.requests = append(this.requests, Request{
+		GrantType:        grantType,
+		ClientId:         request.PostForm.Get("client_id"),
+		RequestedSubject: request.PostForm.Get("requested_subject"),
+		Form:             request.PostForm,
+	})
+	for _, injected := range this.injected {
+		if injected.remaining > 0 && (injected.grantType == "" || injected.grantType == grantType) {
+			injected.remaining--
+			writeError(writer, injected.statusCode, "injected_error", "injected error")
+			return
+		}
+	}
+	if !this.authenticateClient(request.PostForm) {
+		writeError(writer, http.StatusUnauthorized, "unauthorized_client", "Invalid client or Invalid client credentials")
+		return
+	}
+
+	switch grantType {
+	case "client_credentials":
+		this.respond(writer, this.ClientSubject(), this.accessTokenExpiry, true, nil)
+	case "refresh_token":
+		s, ok := this.refreshTokens[request.PostForm.Get("refresh_token")]
+		if !ok || !s.expires.After(this.now()) {
+			writeError(writer, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+			return
+		}
+		this.respond(writer, s.subject, this.accessTokenExpiry, true, nil)
+	case TokenExchangeGrantType:
+		subject := request.PostForm.Get("requested_subject")
+		if subject == "" {
+			writeError(writer, http.StatusBadRequest, "invalid_request", "missing requested_subject")
+			return
+		}
+		if this.forbiddenExchanges[subject] {
+			writeError(writer, http.StatusForbidden, "access_denied", "Client not allowed to exchange")
+			return
+		}
+		claims := jwt.MapClaims{}
+		if audience := request.PostForm.Get("audience"); audience != "" {
+			claims["aud"] = audience
+		}
+		if scope := request.PostForm.Get("scope"); scope != "" {
+			claims["scope"] = scope
+		}
+		this.respond(writer, subject, this.exchangeExpiry, false, claims)
+	default:
+		writeError(writer, http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+	}
+}
+
+// authenticateClient expects this.mux to be locked
+func (this *Server) authenticateClient(form url.Values) bool {
+	if form.Get("client_id") != this.clientId {
+		return false
+	}
+	if form.Get("client_assertion_type") == "" {
+		return form.Get("client_secret") == this.clientSecret
+	}
+	if this.clientPublicKey == nil || form.Get("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+		return false
+	}
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{"RS256"}, SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(form.Get("client_assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+		return this.clientPublicKey, nil
+	})
+	if err != nil {
+		return false
+	}
+	return claims["iss"] == this.clientId && claims["sub"] == this.clientId &&
+		claims.VerifyAudience(this.Issuer()+TokenPath, true) && claims.VerifyExpiresAt(this.now().Unix(), true)
+}
+
+// respond expects this.mux to be locked
+func (this *Server) respond(writer http.ResponseWriter, subject string, expiresIn time.Duration, withRefreshToken bool, claims jwt.MapClaims) {
+	accessToken, err := this.mint(subject, "Bearer", expiresIn, claims)
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	response := map[string]interface{}{
+		"access_token": accessToken,
+		"expires_in":   int64(expiresIn.Seconds()),
+		"token_type":   "Bearer",
+	}
+	if withRefreshToken {
+		refreshToken, err := this.mint(subject, "Refresh", this.refreshTokenExpiry, nil)
+		if err != nil {
+			writeError(writer, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		this.refreshTokens[refreshToken] = session{subject: subject, expires: this.now().Add(this.refreshTokenExpiry)}
+		response["refresh_token"] = refreshToken
+		response["refresh_expires_in"] = int64(this.refreshTokenExpiry.Seconds())
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(response)
+}
+
+// mint expects this.mux to be locked; additional claims overwrite the defaults
+func (this *Server) mint(subject string, tokenType string, expiresIn time.Duration, additional jwt.MapClaims) (string, error) {
+	this.tokenCount++
+	now := this.now()
+	roles := this.roles[subject]
+	if roles == nil {
+		roles = []string{}
+	}
+	claims := jwt.MapClaims{
+		"jti":                strconv.Itoa(this.tokenCount),
+		"iss":                this.Issuer(),
+		"aud":                "account",
+		"sub":                subject,
+		"typ":                tokenType,
+		"azp":                this.clientId,
+		"iat":                now.Unix(),
+		"nbf":                now.Unix(),
+		"exp":                now.Add(expiresIn).Unix(),
+		"preferred_username": subject,
+		"realm_access":       map[string]interface{}{"roles": roles},
+	}
+	for key, value := range additional {
+		claims[key] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = this.keyId
+	return token.SignedString(this.key)
+}
+
+func (this *Server) openidConfiguration(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(map[string]interface{}{
+		"issuer":                                this.Issuer(),
+		"token_endpoint":                        this.Issuer() + TokenPath,
+		"jwks_uri":                              this.Issuer() + CertsPath,
+		"grant_types_supported":                 []string{"client_credentials", "refresh_token", TokenExchangeGrantType},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (this *Server) certs(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": this.keyId,
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(this.key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(this.key.PublicKey.E)).Bytes()),
+		}},
+	})
+}
+
+func writeError(writer http.ResponseWriter, statusCode int, errorCode string, description string) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_ = json.NewEncoder(writer).Encode(map[string]string{"error": errorCode, "error_description": description})
+}
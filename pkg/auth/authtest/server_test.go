@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authtest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestServer(t *testing.T) {
+	server, err := NewServer("client", "secret")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	server.SetExpiries(15*time.Second, time.Minute, time.Minute)
+	server.SetRoles(server.ClientSubject(), "admin")
+
+	now := time.Now()
+	defer func() {
+		auth.TimeNow = time.Now
+	}()
+	auth.TimeNow = func() time.Time {
+		return now
+	}
+
+	a := auth.New(configuration.Config{AuthEndpoint: server.URL, AuthClientId: "client", AuthClientSecret: "secret", TokenCacheDefaultExpirationInSeconds: 60, LogLevel: "error"})
+
+	t.Run("client credentials", func(t *testing.T) {
+		token, err := a.Ensure()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if token.GetUserId() != server.ClientSubject() || !token.IsAdmin() {
+			t.Error(token)
+			return
+		}
+		_, err = jwt.Parse(strings.TrimPrefix(token.Jwt(), "Bearer "), func(token *jwt.Token) (interface{}, error) {
+			return server.PublicKey(), nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		now = now.Add(10 * time.Second)
+		_, err := a.Ensure()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		grants := []string{}
+		for _, request := range server.Requests() {
+			grants = append(grants, request.GrantType)
+		}
+		if strings.Join(grants, ",") != "client_credentials,refresh_token" {
+			t.Error(grants)
+		}
+	})
+
+	t.Run("exchange", func(t *testing.T) {
+		token, err := a.ExchangeUserToken("user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if token.GetUserId() != "user" {
+			t.Error(token)
+		}
+	})
+
+	t.Run("forbidden exchange", func(t *testing.T) {
+		server.ForbidExchange("forbidden")
+		_, err := a.ExchangeUserToken("forbidden")
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("injected error", func(t *testing.T) {
+		server.InjectError(TokenExchangeGrantType, http.StatusBadGateway, 1)
+		_, err := a.ExchangeUserToken("user2")
+		if err == nil {
+			t.Error("expected error")
+			return
+		}
+		// 5xx responses are remembered shortly by auth.Auth
+		a.InvalidateUserToken("user2")
+		_, err = a.ExchangeUserToken("user2")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+}
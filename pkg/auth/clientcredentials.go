@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ClientSecretSource uses the client_credentials grant with client_id and client_secret;
+// it prefers the refresh-token of the current token if it is still valid
+type ClientSecretSource struct {
+	ClientId     string
+	ClientSecret string
+}
+
+func (this *ClientSecretSource) ClientAuthentication(TokenEndpoint) (url.Values, error) {
+	return url.Values{
+		"client_id":     {this.ClientId},
+		"client_secret": {this.ClientSecret},
+	}, nil
+}
+
+func (this *ClientSecretSource) Token(endpoint TokenEndpoint, current OpenidToken) (OpenidToken, error) {
+	return clientCredentials(endpoint, this, current)
+}
+
+// PrivateKeyJwtSource uses the client_credentials grant and authenticates the client
+// with a client assertion signed by its private key (private_key_jwt, RFC 7523)
+type PrivateKeyJwtSource struct {
+	ClientId string
+	KeyId    string //optional kid header of the assertion
+	Key      *rsa.PrivateKey
+}
+
+// NewPrivateKeyJwtSource parses a PEM encoded RSA private key (PKCS #1 or PKCS #8)
+func NewPrivateKeyJwtSource(clientId string, keyId string, pemKey []byte) (*PrivateKeyJwtSource, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pemKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid client private key: %v", ErrInvalidClientCredentials, err)
+	}
+	return &PrivateKeyJwtSource{ClientId: clientId, KeyId: keyId, Key: key}, nil
+}
+
+func (this *PrivateKeyJwtSource) ClientAuthentication(endpoint TokenEndpoint) (url.Values, error) {
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	if err != nil {
+		return nil, err
+	}
+	now := TimeNow()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		Id:        hex.EncodeToString(jti),
+		Issuer:    this.ClientId,
+		Subject:   this.ClientId,
+		Audience:  endpoint.Url(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	})
+	if this.KeyId != "" {
+		assertion.Header["kid"] = this.KeyId
+	}
+	signed, err := assertion.SignedString(this.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to sign client assertion: %v", ErrInvalidClientCredentials, err)
+	}
+	return url.Values{
+		"client_id":             {this.ClientId},
+		"client_assertion_type": {ClientAssertionType},
+		"client_assertion":      {signed},
+	}, nil
+}
+
+func (this *PrivateKeyJwtSource) Token(endpoint TokenEndpoint, current OpenidToken) (OpenidToken, error) {
+	return clientCredentials(endpoint, this, current)
+}
+
+func clientCredentials(endpoint TokenEndpoint, client ClientAuthenticator, current OpenidToken) (token OpenidToken, err error) {
+	duration := TimeNow().Sub(current.RequestTime).Seconds()
+	if current.RefreshToken != "" && current.RefreshExpiresIn-expirationBuffer > duration {
+		values, err := client.ClientAuthentication(endpoint)
+		if err != nil {
+			return token, err
+		}
+		values.Set("grant_type", "refresh_token")
+		values.Set("refresh_token", current.RefreshToken)
+		token, err = endpoint.Request(values)
+		if err == nil {
+			return token, nil
+		}
+		// the refresh token may have been revoked; fall back to a new token
+	}
+	values, err := client.ClientAuthentication(endpoint)
+	if err != nil {
+		return token, err
+	}
+	values.Set("grant_type", "client_credentials")
+	return endpoint.Request(values)
+}
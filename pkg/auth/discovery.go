@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"golang.org/x/sync/singleflight"
+)
+
+// discovered endpoints are cached for discoveryMaxAge; after a failed discovery the fallback is used for discoveryRetryInterval
+const discoveryMaxAge = time.Hour
+const discoveryRetryInterval = time.Minute
+
+// metadataTimeout bounds the discovery and jwks requests of the default http client
+const metadataTimeout = 10 * time.Second
+
+func newMetadataHttpClient() *http.Client {
+	return &http.Client{Timeout: metadataTimeout}
+}
+
+// withMetadataTimeout returns a copy of client with metadataTimeout, if client has no timeout
+func withMetadataTimeout(client *http.Client) *http.Client {
+	if client.Timeout != 0 {
+		return client
+	}
+	result := *client
+	result.Timeout = metadataTimeout
+	return &result
+}
+
+// Endpoints are the OpenID Connect endpoints of the realm
+type Endpoints struct {
+	Issuer        string `json:"issuer"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JwksUri       string `json:"jwks_uri"`
+}
+
+// discovery resolves the Endpoints with .well-known/openid-configuration.
+// The issuer is config.AuthTokenIssuer or, if not set, config.AuthEndpoint + "/auth/realms/" + realm
+// (Keycloak < 17) or config.AuthEndpoint + "/realms/" + realm (Keycloak >= 17).
+// If no discovery succeeds, the legacy layout below config.AuthEndpoint + "/auth/realms/" + realm is used.
+type discovery struct {
+	config     configuration.Config
+	httpClient *http.Client
+	flight     singleflight.Group
+	mux        sync.Mutex
+	endpoints  *Endpoints
+	loadedAt   time.Time
+	failedAt   time.Time
+}
+
+func newDiscovery(config configuration.Config) *discovery {
+	return &discovery{config: config, httpClient: newMetadataHttpClient()}
+}
+
+func (this *discovery) realm() string {
+	if this.config.AuthRealm == "" {
+		return "master"
+	}
+	return this.config.AuthRealm
+}
+
+func (this *discovery) setHttpClient(client *http.Client) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.httpClient = client
+}
+
+func (this *discovery) fallback() Endpoints {
+	realmUrl := strings.TrimSuffix(this.config.AuthEndpoint, "/") + "/auth/realms/" + this.realm()
+	issuer := this.configuredIssuer()
+	if issuer == "" {
+		issuer = realmUrl
+	}
+	return Endpoints{
+		Issuer:        issuer,
+		TokenEndpoint: realmUrl + "/protocol/openid-connect/token",
+		JwksUri:       realmUrl + "/protocol/openid-connect/certs",
+	}
+}
+
+// configuredIssuer returns config.AuthTokenIssuer without trailing slash, like the iss claim of the realm tokens
+func (this *discovery) configuredIssuer() string {
+	return strings.TrimSuffix(this.config.AuthTokenIssuer, "/")
+}
+
+func (this *discovery) issuerCandidates() []string {
+	if issuer := this.configuredIssuer(); issuer != "" {
+		return []string{issuer}
+	}
+	base := strings.TrimSuffix(this.config.AuthEndpoint, "/")
+	return []string{base + "/auth/realms/" + this.realm(), base + "/realms/" + this.realm()}
+}
+
+// Get returns the cached endpoints, discovers them if necessary or returns the fallback.
+// Concurrent discoveries are deduplicated; requests run without holding the lock.
+func (this *discovery) Get() Endpoints {
+	if endpoints, ok := this.cached(); ok {
+		return endpoints
+	}
+	result, _, _ := this.flight.Do("discover", func() (interface{}, error) {
+		if endpoints, ok := this.cached(); ok {
+			return endpoints, nil // discovered by the previous flight
+		}
+		return this.refresh(), nil
+	})
+	return result.(Endpoints)
+}
+
+// cached returns the endpoints, if no discovery is needed: discovered endpoints younger than discoveryMaxAge,
+// or after a failed discovery the previous endpoints or the fallback
+func (this *discovery) cached() (Endpoints, bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	now := TimeNow()
+	if this.endpoints != nil && now.Sub(this.loadedAt) < discoveryMaxAge {
+		return *this.endpoints, true
+	}
+	if !this.failedAt.IsZero() && now.Sub(this.failedAt) < discoveryRetryInterval {
+		if this.endpoints != nil {
+			return *this.endpoints, true
+		}
+		return this.fallback(), true
+	}
+	return Endpoints{}, false
+}
+
+func (this *discovery) refresh() Endpoints {
+	this.mux.Lock()
+	client := this.httpClient
+	this.mux.Unlock()
+	for _, issuer := range this.issuerCandidates() {
+		endpoints, err := this.discover(client, issuer)
+		if err != nil {
+			this.config.GetLogger().Debug("unable to discover openid configuration", "issuer", issuer, "error", err)
+			continue
+		}
+		this.mux.Lock()
+		this.endpoints = &endpoints
+		this.loadedAt = TimeNow()
+		this.failedAt = time.Time{}
+		this.mux.Unlock()
+		return endpoints
+	}
+	this.config.GetLogger().Warn("unable to discover openid configuration; use fallback", "fallback", this.fallback())
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.failedAt = TimeNow()
+	if this.endpoints != nil {
+		return *this.endpoints
+	}
+	return this.fallback()
+}
+
+func (this *discovery) discover(client *http.Client, issuer string) (result Endpoints, err error) {
+	resp, err := client.Get(issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return result, fmt.Errorf("unexpected response: %v %v", resp.StatusCode, string(body))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return result, err
+	}
+	if result.TokenEndpoint == "" || result.JwksUri == "" {
+		return result, fmt.Errorf("incomplete openid configuration: %#v", result)
+	}
+	if configured := this.configuredIssuer(); configured != "" {
+		result.Issuer = configured
+	}
+	return result, nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+)
+
+func TestDiscovery(t *testing.T) {
+	t.Run("legacy layout", func(t *testing.T) {
+		server := newTestServer(t)
+		testDiscovery(t, server, configuration.Config{AuthEndpoint: server.URL})
+	})
+	t.Run("realm without auth prefix", func(t *testing.T) {
+		server := newTestServer(t, authtest.WithRealmPath("/realms/senergy"))
+		testDiscovery(t, server, configuration.Config{AuthEndpoint: server.URL, AuthRealm: "senergy"})
+	})
+	t.Run("configured issuer", func(t *testing.T) {
+		server := newTestServer(t, authtest.WithRealmPath("/custom/path"))
+		testDiscovery(t, server, configuration.Config{AuthEndpoint: server.URL, AuthTokenIssuer: server.Issuer()})
+	})
+	t.Run("fallback", func(t *testing.T) {
+		server := newTestServer(t, authtest.WithRealmPath("/auth/realms/senergy"), authtest.WithoutDiscovery())
+		testDiscovery(t, server, configuration.Config{AuthEndpoint: server.URL, AuthRealm: "senergy"})
+	})
+}
+
+func testDiscovery(t *testing.T, server *authtest.Server, config configuration.Config) {
+	config.AuthClientId = "client"
+	config.AuthClientSecret = "secret"
+	config.AuthVerifyTokens = true
+	config.TokenCacheDefaultExpirationInSeconds = 60
+	config.LogLevel = "error"
+	a := New(config)
+
+	endpoints := a.Endpoints()
+	if endpoints.Issuer != server.Issuer() || endpoints.TokenEndpoint != server.Issuer()+authtest.TokenPath || endpoints.JwksUri != server.Issuer()+authtest.CertsPath {
+		t.Error(endpoints)
+		return
+	}
+	token, err := a.Ensure()
+	if err != nil || token.GetUserId() != server.ClientSubject() {
+		t.Error(err, token)
+		return
+	}
+	token, err = a.ExchangeUserToken("user")
+	if err != nil || token.GetUserId() != "user" {
+		t.Error(err, token)
+		return
+	}
+}
+
+// run with -race
+func TestConcurrentDiscovery(t *testing.T) {
+	requests := atomic.Int64{}
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests.Add(1)
+		<-release
+		issuer := "http://" + request.Host + "/auth/realms/master"
+		_ = json.NewEncoder(writer).Encode(Endpoints{Issuer: issuer, TokenEndpoint: issuer + "/token", JwksUri: issuer + "/certs"})
+	}))
+	defer server.Close()
+
+	d := newDiscovery(configuration.Config{AuthEndpoint: server.URL, LogLevel: "error"})
+	if d.httpClient.Timeout == 0 {
+		t.Error("default http client should have a timeout")
+	}
+
+	wg := sync.WaitGroup{}
+	results := make(chan Endpoints, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- d.Get()
+		}()
+	}
+	for requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the lock is not held during the request
+	locked := make(chan struct{})
+	go func() {
+		_, _ = d.cached()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("discovery request should not hold the lock")
+	}
+
+	close(release)
+	wg.Wait()
+	close(results)
+	for endpoints := range results {
+		if endpoints.TokenEndpoint != server.URL+"/auth/realms/master/token" {
+			t.Error(endpoints)
+		}
+	}
+	if count := requests.Load(); count != 1 {
+		t.Error("concurrent discoveries should be deduplicated", count)
+	}
+}
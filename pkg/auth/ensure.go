@@ -18,99 +18,109 @@ package auth
 
 import (
 	"encoding/json"
-	"errors"
-	"io"
+	"fmt"
 	"net/http"
 	"net/url"
-
-	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 )
 
 func (this *Auth) Ensure() (token Token, err error) {
+	token, ok := this.currentToken()
+	if ok {
+		return token, nil
+	}
+	// all callers that find an expired token share one fetch
+	result, err, _ := this.flight.Do("openid", func() (interface{}, error) {
+		token, ok := this.currentToken()
+		if ok {
+			return token, nil
+		}
+		return this.renewOpenidToken(false)
+	})
+	return result.(Token), err
+}
+
+// InvalidateToken drops the client token if it is still the current one, e.g. after a service rejected it with 401;
+// the next Ensure renews it (with the refresh-token if possible)
+func (this *Auth) InvalidateToken(token Token) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.openid != nil && this.openid.ParsedToken.Token == token.Token {
+		invalidated := *this.openid
+		invalidated.AccessToken = ""
+		this.openid = &invalidated
+	}
+}
+
+// subtract 10 seconds from expiration as a buffer
+const expirationBuffer = 10.0
+
+func (this *Auth) currentToken() (token Token, ok bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
 	if this.openid == nil {
-		this.openid = &OpenidToken{}
+		return token, false
 	}
 	duration := TimeNow().Sub(this.openid.RequestTime).Seconds()
+	if this.openid.AccessToken != "" && this.openid.ExpiresIn-expirationBuffer > duration {
+		return this.openid.ParsedToken, true
+	}
+	return token, false
+}
 
-	// subtract 5 seconds from expiration as a buffer
-	buffer := 10.0
-
-	if this.openid.AccessToken != "" && this.openid.ExpiresIn-buffer > duration {
-		return this.openid.ParsedToken, nil
+// renewOpenidToken requests a new token from the TokenSource (which may use the refresh-token of the current token);
+// this.openid is only replaced after the request is finished.
+// if keepOnError is set (proactive renewal of a still valid token), a failed request does not reset this.openid
+func (this *Auth) renewOpenidToken(keepOnError bool) (token Token, err error) {
+	this.mux.Lock()
+	current := OpenidToken{}
+	if this.openid != nil {
+		current = *this.openid
 	}
+	source := this.source
+	this.mux.Unlock()
 
-	// subtract 5 seconds from expiration as a buffer
-	if this.openid.RefreshToken != "" && this.openid.RefreshExpiresIn-buffer > duration {
-		this.config.GetLogger().Debug("refresh token", "duration", duration, "refresh-expires-in", this.openid.RefreshExpiresIn)
-		err = refreshOpenidToken(this.openid, this.config)
+	this.config.GetLogger().Debug("renew access token")
+	renewed, err := source.Token(tokenEndpoint{auth: this}, current)
+	if err == nil {
+		renewed.ParsedToken, err = this.parse(renewed.AccessToken)
 		if err != nil {
-			this.config.GetLogger().Warn("unable to use refresh-token", "error", err)
-		} else {
-			return this.openid.ParsedToken, nil
+			err = fmt.Errorf("%w: %w", ErrAuth, err)
 		}
 	}
-
-	this.config.GetLogger().Debug("get new access token")
-	err = getOpenidToken(this.openid, this.config)
 	if err != nil {
 		this.config.GetLogger().Error("unable to get new access token", "error", err)
-		this.openid = &OpenidToken{}
+		if !keepOnError {
+			this.setOpenidToken(&OpenidToken{})
+		}
+		return token, err
 	}
-	return this.openid.ParsedToken, nil
+	this.setOpenidToken(&renewed)
+	return renewed.ParsedToken, nil
 }
 
-func getOpenidToken(token *OpenidToken, config configuration.Config) (err error) {
-	requesttime := TimeNow()
-	resp, err := http.PostForm(config.AuthEndpoint+"/auth/realms/master/protocol/openid-connect/token", url.Values{
-		"client_id":     {config.AuthClientId},
-		"client_secret": {config.AuthClientSecret},
-		"grant_type":    {"client_credentials"},
-	})
-
-	if err != nil {
-		config.GetLogger().Error("error in getOpenidToken::PostForm()", "error", err)
-		return err
-	}
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		config.GetLogger().Error("error in getOpenidToken()", "statuscode", resp.StatusCode, "response", string(body))
-		err = errors.New("access denied")
-		resp.Body.Close()
-		return
-	}
-	err = json.NewDecoder(resp.Body).Decode(token)
-	if err != nil {
-		return err
-	}
-	token.RequestTime = requesttime
-	token.ParsedToken, err = Parse(token.AccessToken)
-	return
+func (this *Auth) setOpenidToken(token *OpenidToken) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.openid = token
 }
 
-func refreshOpenidToken(token *OpenidToken, config configuration.Config) (err error) {
+func (this *Auth) requestOpenidToken(token *OpenidToken, values url.Values) (err error) {
 	requesttime := TimeNow()
-	resp, err := http.PostForm(config.AuthEndpoint+"/auth/realms/master/protocol/openid-connect/token", url.Values{
-		"client_id":     {config.AuthClientId},
-		"client_secret": {config.AuthClientSecret},
-		"refresh_token": {token.RefreshToken},
-		"grant_type":    {"refresh_token"},
-	})
-
+	resp, err := this.httpClient.PostForm(this.discovery.Get().TokenEndpoint, values)
 	if err != nil {
-		return err
+		this.config.GetLogger().Error("error in requestOpenidToken::PostForm()", "error", err, "grant_type", values.Get("grant_type"))
+		return fmt.Errorf("%w: %v", ErrAuthServerUnreachable, err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		config.GetLogger().Error("error in refreshOpenidToken()", "statuscode", resp.StatusCode, "response", string(body))
-		err = errors.New("access denied")
-		resp.Body.Close()
-		return
+		err = tokenResponseError(resp, false)
+		this.config.GetLogger().Error("error in requestOpenidToken()", "statuscode", resp.StatusCode, "grant_type", values.Get("grant_type"), "error", err)
+		return err
 	}
 	err = json.NewDecoder(resp.Body).Decode(token)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: unable to decode token response: %v", ErrAuth, err)
 	}
 	token.RequestTime = requesttime
-	token.ParsedToken, err = Parse(token.AccessToken)
-	return
+	return nil
 }
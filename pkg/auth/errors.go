@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrAuth is wrapped by every error returned by Auth.Ensure, Auth.ExchangeUserToken and Auth.ExchangeUserTokenWith,
+// so callers can tell auth failures apart from failures of the service they want to call
+var ErrAuth = errors.New("auth error")
+
+var ErrAuthServerUnreachable = fmt.Errorf("%w: auth server unreachable", ErrAuth)
+var ErrInvalidClientCredentials = fmt.Errorf("%w: invalid client credentials", ErrAuth)
+var ErrExchangeForbidden = fmt.Errorf("%w: token exchange forbidden", ErrAuth)
+var ErrAccessDenied = fmt.Errorf("%w: access denied", ErrAuth)
+var ErrExchangeUnsupported = fmt.Errorf("%w: token exchange is not supported by the token source", ErrAuth)
+
+// tokenResponseError reads the error response of the token endpoint and returns the matching typed error
+func tokenResponseError(resp *http.Response, exchange bool) error {
+	body, _ := io.ReadAll(resp.Body)
+	oauthErr := struct {
+		Error string `json:"error"`
+	}{}
+	_ = json.Unmarshal(body, &oauthErr)
+	var kind error
+	switch {
+	case resp.StatusCode >= 500:
+		kind = ErrAuthServerUnreachable
+	case oauthErr.Error == "invalid_client" || oauthErr.Error == "unauthorized_client" || resp.StatusCode == http.StatusUnauthorized:
+		kind = ErrInvalidClientCredentials
+	case exchange && (resp.StatusCode == http.StatusForbidden || oauthErr.Error == "access_denied"):
+		kind = ErrExchangeForbidden
+	default:
+		kind = ErrAccessDenied
+	}
+	return fmt.Errorf("%w: %v %v", kind, resp.StatusCode, string(body))
+}
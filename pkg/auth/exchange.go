@@ -19,43 +19,122 @@ package auth
 import (
 	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache"
 )
 
+// ExchangeOptions are optional parameters of the token exchange (RFC 8693); empty fields are not sent
+type ExchangeOptions struct {
+	Audience           string // client id of the target service, sets the aud claim of the exchanged token
+	Scope              string // space separated scopes
+	RequestedTokenType string // e.g. "urn:ietf:params:oauth:token-type:access_token"
+}
+
+func (this ExchangeOptions) values() url.Values {
+	result := url.Values{}
+	if this.Audience != "" {
+		result.Set("audience", this.Audience)
+	}
+	if this.Scope != "" {
+		result.Set("scope", this.Scope)
+	}
+	if this.RequestedTokenType != "" {
+		result.Set("requested_token_type", this.RequestedTokenType)
+	}
+	return result
+}
+
+// forbidden exchanges are remembered for this duration, to avoid repeated requests that can not succeed
+const forbiddenExchangeCacheDuration = 5 * time.Second
+
+// exchanges failing because the auth server is unreachable (or responds with 5xx) are remembered shortly, to not hit an outage with every request
+const unreachableExchangeCacheDuration = time.Second
+
 func (this *Auth) ExchangeUserToken(userid string) (token Token, err error) {
-	err = this.cache.UseWithExpirationInResult("user-token."+userid, func() (interface{}, time.Duration, error) {
-		return this.exchangeUserToken(userid)
-	}, &token)
-	return token, err
+	return this.ExchangeUserTokenWith(userid, ExchangeOptions{})
 }
 
-func (this *Auth) exchangeUserToken(userid string) (token Token, expiration time.Duration, err error) {
-	resp, err := http.PostForm(this.config.AuthEndpoint+"/auth/realms/master/protocol/openid-connect/token", url.Values{
-		"client_id":         {this.config.AuthClientId},
-		"client_secret":     {this.config.AuthClientSecret},
-		"grant_type":        {"urn:ietf:params:oauth:grant-type:token-exchange"},
-		"requested_subject": {userid},
-	})
+// ExchangeUserTokenWith exchanges a token for userid with the given options;
+// tokens are cached per user and options
+func (this *Auth) ExchangeUserTokenWith(userid string, opts ExchangeOptions) (token Token, err error) {
+	key := exchangeCacheKey(userid, opts)
+	// concurrent cache misses for the same user share one exchange
+	token, err = this.cache.UseWithExpirationInResult(key, func() (Token, time.Duration, error) {
+		token, expiration, err := this.exchangeUserToken(userid, opts)
+		if err == nil {
+			this.trackRenewal(key, userid, opts, expiration)
+		}
+		return token, expiration, err
+	}, cache.WithNegativeCaching(forbiddenExchangeCacheDuration, func(err error) bool {
+		return errors.Is(err, ErrExchangeForbidden)
+	}), cache.WithNegativeCaching(unreachableExchangeCacheDuration, func(err error) bool {
+		return errors.Is(err, ErrAuthServerUnreachable)
+	}))
 	if err != nil {
-		return
+		return token, err
 	}
+	this.trackUsage(key)
+	return token, nil
+}
+
+// InvalidateUserToken drops the cached exchanged token of userid, e.g. after a service rejected it with 401
+func (this *Auth) InvalidateUserToken(userid string) {
+	this.InvalidateUserTokenWith(userid, ExchangeOptions{})
+}
+
+// InvalidateUserTokenWith drops the cached token exchanged with ExchangeUserTokenWith(userid, opts)
+func (this *Auth) InvalidateUserTokenWith(userid string, opts ExchangeOptions) {
+	this.cache.Invalidate(exchangeCacheKey(userid, opts))
+}
+
+func exchangeCacheKey(userid string, opts ExchangeOptions) string {
+	key := "user-token." + userid
+	if params := opts.values(); len(params) > 0 {
+		key = key + "?" + params.Encode()
+	}
+	return key
+}
+
+func (this *Auth) exchangeUserToken(userid string, opts ExchangeOptions) (token Token, expiration time.Duration, err error) {
+	this.mux.Lock()
+	client, ok := this.source.(ClientAuthenticator)
+	this.mux.Unlock()
+	if !ok {
+		return token, expiration, ErrExchangeUnsupported
+	}
+	values, err := client.ClientAuthentication(tokenEndpoint{auth: this})
+	if err != nil {
+		return token, expiration, err
+	}
+	for key, value := range opts.values() {
+		values[key] = value
+	}
+	values.Set("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+	values.Set("requested_subject", userid)
+	resp, err := this.httpClient.PostForm(this.discovery.Get().TokenEndpoint, values)
+	if err != nil {
+		return token, expiration, fmt.Errorf("%w: %v", ErrAuthServerUnreachable, err)
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		this.config.GetLogger().Error("error in GetUserToken()", "statuscode", resp.StatusCode, "response", string(body))
-		err = errors.New("access denied")
-		resp.Body.Close()
-		return
+		err = tokenResponseError(resp, true)
+		this.config.GetLogger().Error("error in GetUserToken()", "statuscode", resp.StatusCode, "userId", userid, "audience", opts.Audience, "scope", opts.Scope, "error", err)
+		return token, expiration, err
 	}
 	var openIdToken OpenidToken
 	err = json.NewDecoder(resp.Body).Decode(&openIdToken)
 	if err != nil {
-		return
+		return token, expiration, fmt.Errorf("%w: unable to decode token response: %v", ErrAuth, err)
+	}
+	token, err = this.parse("Bearer " + openIdToken.AccessToken)
+	if err != nil {
+		return token, expiration, fmt.Errorf("%w: %w", ErrAuth, err)
 	}
-	token, err = Parse("Bearer " + openIdToken.AccessToken)
-	return token, (time.Duration(openIdToken.ExpiresIn - 5)) * time.Second, err // subtract 5 seconds from expiration as a buffer
+	return token, (time.Duration(openIdToken.ExpiresIn - 5)) * time.Second, nil // subtract 5 seconds from expiration as a buffer
 }
 
 type OpenidToken struct {
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RefresherSettings control the background refresh started with Auth.StartRefresher
+type RefresherSettings struct {
+	Interval    time.Duration // time between checks
+	Ahead       time.Duration // tokens expiring within this duration are renewed
+	MinUses     int           // exchanged tokens are renewed if they have been used at least MinUses times during their lifetime
+	IdleTimeout time.Duration // exchanged tokens not used for this duration are no longer renewed
+	MaxBackoff  time.Duration // the interval is doubled after each failed check, up to MaxBackoff
+}
+
+var DefaultRefresherSettings = RefresherSettings{
+	Interval:    5 * time.Second,
+	Ahead:       30 * time.Second,
+	MinUses:     2,
+	IdleTimeout: 5 * time.Minute,
+	MaxBackoff:  time.Minute,
+}
+
+type usage struct {
+	userid    string
+	opts      ExchangeOptions
+	uses      int
+	lastUsed  time.Time
+	expiresAt time.Time
+	lifetime  time.Duration
+}
+
+// StartRefresher renews the client token and frequently used exchanged user tokens before they expire,
+// so that callers of Ensure and ExchangeUserToken rarely wait for the auth server.
+// The refresher backs off on errors and stops when ctx is done.
+func (this *Auth) StartRefresher(ctx context.Context, wg *sync.WaitGroup, settings RefresherSettings) {
+	this.mux.Lock()
+	if this.usages == nil {
+		this.usages = map[string]*usage{}
+	}
+	this.mux.Unlock()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wait := settings.Interval
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			err := this.refresh(settings)
+			if err != nil {
+				wait = min(wait*2, settings.MaxBackoff)
+				this.config.GetLogger().Warn("unable to refresh tokens in background", "error", err, "retry", wait.String())
+			} else {
+				wait = settings.Interval
+			}
+			timer.Reset(wait)
+		}
+	}()
+}
+
+func (this *Auth) trackUsage(key string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if u, ok := this.usages[key]; ok {
+		u.uses++
+		u.lastUsed = TimeNow()
+	}
+}
+
+// trackRenewal starts a new lifetime of the exchanged token
+func (this *Auth) trackRenewal(key string, userid string, opts ExchangeOptions, expiration time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.usages == nil {
+		return
+	}
+	u, ok := this.usages[key]
+	if !ok {
+		u = &usage{userid: userid, opts: opts, lastUsed: TimeNow()}
+		this.usages[key] = u
+	}
+	u.uses = 0
+	u.expiresAt = TimeNow().Add(expiration)
+	u.lifetime = expiration
+}
+
+func (this *Auth) refresh(settings RefresherSettings) error {
+	now := TimeNow()
+	errs := []error{}
+
+	this.mux.Lock()
+	renewClientToken := false
+	if this.openid != nil && this.openid.AccessToken != "" {
+		lifetime := time.Duration(this.openid.ExpiresIn * float64(time.Second))
+		renewClientToken = isDue(this.openid.RequestTime.Add(lifetime).Sub(now), lifetime, settings.Ahead)
+	}
+	due := map[string]usage{}
+	for key, u := range this.usages {
+		if now.Sub(u.lastUsed) > settings.IdleTimeout {
+			delete(this.usages, key)
+			continue
+		}
+		if u.uses >= settings.MinUses && isDue(u.expiresAt.Sub(now), u.lifetime, settings.Ahead) {
+			due[key] = *u
+		}
+	}
+	this.mux.Unlock()
+
+	if renewClientToken {
+		_, err, _ := this.flight.Do("openid", func() (interface{}, error) {
+			return this.renewOpenidToken(true)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for key, u := range due {
+		_, err := this.cache.Reload(key, func() (Token, time.Duration, error) {
+			token, expiration, err := this.exchangeUserToken(u.userid, u.opts)
+			if err == nil {
+				this.trackRenewal(key, u.userid, u.opts, expiration)
+			}
+			return token, expiration, err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isDue limits ahead to half of the lifetime, so that short-lived tokens are not renewed on every check
+func isDue(remaining time.Duration, lifetime time.Duration, ahead time.Duration) bool {
+	return remaining <= min(ahead, lifetime/2)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+)
+
+type testClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+func (this *testClock) Now() time.Time {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.now
+}
+
+func (this *testClock) Add(d time.Duration) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.now = this.now.Add(d)
+}
+
+// run with -race
+func TestRefresher(t *testing.T) {
+	server := newTestServer(t)
+	server.SetExpiries(time.Minute, 10*time.Minute, time.Minute)
+
+	clock := &testClock{now: time.Now()}
+	server.SetNow(clock.Now)
+	TimeNow = clock.Now
+	defer func() {
+		TimeNow = time.Now
+	}()
+
+	countGrants := func() map[string]int {
+		result := map[string]int{}
+		for _, request := range server.Requests() {
+			result[request.GrantType+"/"+request.RequestedSubject]++
+		}
+		return result
+	}
+	waitFor := func(cond func() bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return true
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return false
+	}
+
+	a := New(testConfig(server))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	a.StartRefresher(ctx, wg, RefresherSettings{
+		Interval:    10 * time.Millisecond,
+		Ahead:       30 * time.Second,
+		MinUses:     2,
+		IdleTimeout: 5 * time.Minute,
+		MaxBackoff:  50 * time.Millisecond,
+	})
+
+	clientToken, err := a.Ensure()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	frequent, err := a.ExchangeUserToken("frequent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = a.ExchangeUserToken("frequent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = a.ExchangeUserToken("rare")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if grants := countGrants(); grants["refresh_token/"] != 0 || grants[authtest.TokenExchangeGrantType+"/frequent"] != 1 {
+		t.Error("unexpected renewal", grants)
+		return
+	}
+
+	clock.Add(40 * time.Second)
+	if !waitFor(func() bool {
+		grants := countGrants()
+		current, ok := a.currentToken()
+		cached, _ := a.cache.Get(exchangeCacheKey("frequent", ExchangeOptions{}))
+		// requests are recorded before the renewed tokens are stored
+		return grants["refresh_token/"] == 1 && grants[authtest.TokenExchangeGrantType+"/frequent"] == 2 &&
+			ok && current.Jwt() != clientToken.Jwt() && cached.Jwt() != frequent.Jwt()
+	}) {
+		t.Error("missing renewal", countGrants())
+		return
+	}
+	if grants := countGrants(); grants[authtest.TokenExchangeGrantType+"/rare"] != 1 {
+		t.Error("rarely used token should not be renewed", grants)
+		return
+	}
+
+	renewedClientToken, err := a.Ensure()
+	if err != nil || renewedClientToken.Jwt() == clientToken.Jwt() {
+		t.Error("expected renewed client token", err)
+	}
+	renewedUserToken, err := a.ExchangeUserToken("frequent")
+	if err != nil || renewedUserToken.Jwt() == frequent.Jwt() {
+		t.Error("expected renewed user token", err)
+	}
+	if grants := countGrants(); grants[authtest.TokenExchangeGrantType+"/frequent"] != 2 || grants["client_credentials/"] != 1 {
+		t.Error("renewed tokens should be served from cache", grants)
+	}
+
+	t.Run("backoff keeps valid token", func(t *testing.T) {
+		server.InjectError("", 500, 1000)
+		before := len(server.Requests())
+		clock.Add(40 * time.Second)
+		time.Sleep(200 * time.Millisecond)
+		attempts := len(server.Requests()) - before
+		// with a constant interval of 10ms there would be ~20 checks, each with several requests
+		if attempts == 0 || attempts > 20 {
+			t.Error(attempts)
+		}
+		token, err := a.Ensure()
+		if err != nil || token.Jwt() != renewedClientToken.Jwt() {
+			t.Error("still valid token should be kept on refresh errors", err)
+		}
+	})
+}
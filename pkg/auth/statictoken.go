@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTokenFile is the service account token mounted by Kubernetes
+const DefaultTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// tokens without exp claim are treated as valid for this duration
+const staticTokenDefaultExpiration = 365 * 24 * time.Hour
+
+// files are re-read after this duration, to pick up rotated tokens
+const fileTokenMaxAge = time.Minute
+
+// StaticTokenSource returns a fixed token, e.g. for local development;
+// token exchange is not supported
+type StaticTokenSource struct {
+	AccessToken string
+}
+
+func (this *StaticTokenSource) Token(TokenEndpoint, OpenidToken) (OpenidToken, error) {
+	return tokenWithExpiration(this.AccessToken, staticTokenDefaultExpiration)
+}
+
+// FileTokenSource reads the token from a file, e.g. a Kubernetes projected service account token;
+// the file is read again when the token expires or fileTokenMaxAge has passed. Token exchange is not supported.
+type FileTokenSource struct {
+	Path string
+}
+
+func (this *FileTokenSource) Token(TokenEndpoint, OpenidToken) (OpenidToken, error) {
+	content, err := os.ReadFile(this.Path)
+	if err != nil {
+		return OpenidToken{}, fmt.Errorf("%w: unable to read token file: %v", ErrInvalidClientCredentials, err)
+	}
+	return tokenWithExpiration(strings.TrimSpace(string(content)), fileTokenMaxAge)
+}
+
+// tokenWithExpiration uses the exp claim of the token, limited to maxAge; expired tokens are rejected
+func tokenWithExpiration(accessToken string, maxAge time.Duration) (token OpenidToken, err error) {
+	if accessToken == "" {
+		return token, fmt.Errorf("%w: missing token", ErrInvalidClientCredentials)
+	}
+	parsed, err := Parse(accessToken)
+	if err != nil {
+		return token, fmt.Errorf("%w: %v", ErrInvalidClientCredentials, err)
+	}
+	now := TimeNow()
+	expiresIn := maxAge
+	if exp := parsed.ExpiresAt(); !exp.IsZero() {
+		if !now.Before(exp) {
+			return token, fmt.Errorf("%w: %w at %v", ErrInvalidClientCredentials, ErrTokenExpired, exp)
+		}
+		expiresIn = min(exp.Sub(now), maxAge)
+	}
+	return OpenidToken{
+		AccessToken: accessToken,
+		ExpiresIn:   expiresIn.Seconds(),
+		TokenType:   "Bearer",
+		RequestTime: now,
+	}, nil
+}
@@ -17,15 +17,25 @@
 package auth
 
 import (
+	"encoding/json"
 	"errors"
-	"github.com/golang-jwt/jwt"
 	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
 )
 
 type Token struct {
-	Token       string              `json:"__token"`
-	Sub         string              `json:"sub,omitempty"`
-	RealmAccess map[string][]string `json:"realm_access,omitempty"`
+	Token             string                         `json:"__token"`
+	Sub               string                         `json:"sub,omitempty"`
+	RealmAccess       map[string][]string            `json:"realm_access,omitempty"`
+	Exp               int64                          `json:"exp,omitempty"`
+	Iat               int64                          `json:"iat,omitempty"`
+	Azp               string                         `json:"azp,omitempty"`
+	PreferredUsername string                         `json:"preferred_username,omitempty"`
+	Groups            []string                       `json:"groups,omitempty"`
+	ResourceAccess    map[string]map[string][]string `json:"resource_access,omitempty"` //client-id -> "roles" -> client roles
+	Claims            map[string]interface{}         `json:"__claims,omitempty"`        //all claims of the token, including custom claims
 }
 
 func (this *Token) String() string {
@@ -43,18 +53,76 @@ func (this *Token) Valid() error {
 	return nil
 }
 
+// Parse reads the claims of a token (with or without "Bearer " prefix) without checking the signature; see Verifier
 func Parse(token string) (claims Token, err error) {
 	orig := token
 	if len(token) > 7 && strings.ToLower(token[:7]) == "bearer " {
 		token = token[7:]
 	}
-	_, _, err = new(jwt.Parser).ParseUnverified(token, &claims)
-	if err == nil {
-		claims.Token = orig
+	all := jwt.MapClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(token, all)
+	if err != nil {
+		return claims, err
+	}
+	err = decodeClaim(all, "sub", &claims.Sub)
+	if err != nil {
+		return claims, err
+	}
+	err = decodeClaim(all, "realm_access", &claims.RealmAccess)
+	if err != nil {
+		return claims, err
+	}
+	// claims added after sub and realm_access are decoded leniently, so that tokens with unexpected formats are still accepted
+	var exp, iat float64 // NumericDate may contain fractions
+	_ = decodeClaim(all, "exp", &exp)
+	_ = decodeClaim(all, "iat", &iat)
+	claims.Exp, claims.Iat = int64(exp), int64(iat)
+	_ = decodeClaim(all, "azp", &claims.Azp)
+	_ = decodeClaim(all, "preferred_username", &claims.PreferredUsername)
+	_ = decodeClaim(all, "groups", &claims.Groups)
+	_ = decodeClaim(all, "resource_access", &claims.ResourceAccess)
+	claims.Token = orig
+	claims.Claims = all
+	return claims, nil
+}
+
+func decodeClaim(claims jwt.MapClaims, name string, result interface{}) error {
+	value, ok := claims[name]
+	if !ok || value == nil {
+		return nil
+	}
+	temp, err := json.Marshal(value)
+	if err != nil {
+		return err
 	}
-	return
+	return json.Unmarshal(temp, result)
+}
+
+// ExpiresAt returns the exp claim; the zero time.Time if the token has no exp claim
+func (this *Token) ExpiresAt() time.Time {
+	if this.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(this.Exp, 0)
+}
+
+// IsExpired returns true if the token has an exp claim that is not after now
+func (this *Token) IsExpired(now time.Time) bool {
+	return this.Exp != 0 && !now.Before(this.ExpiresAt())
+}
+
+// HasClientRole checks the resource_access roles of the client
+func (this *Token) HasClientRole(client string, role string) bool {
+	return contains(this.ResourceAccess[client]["roles"], role)
+}
+
+// Claim returns any claim of the token by name, e.g. Claim("email")
+func (this *Token) Claim(name string) (value interface{}, ok bool) {
+	value, ok = this.Claims[name]
+	return value, ok
 }
 
+// IsAdmin checks the realm role "admin"; only trust it for tokens from a Verifier or Auth, not for unverified tokens from Parse
 func (this *Token) IsAdmin() bool {
 	return contains(this.GetRoles(), "admin")
 }
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestTokenClaims(t *testing.T) {
+	exp := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":                "user-id",
+		"exp":                exp.Unix(),
+		"iat":                exp.Add(-5 * time.Minute).Unix(),
+		"azp":                "frontend",
+		"preferred_username": "user",
+		"groups":             []string{"/users", "/admins"},
+		"realm_access":       map[string]interface{}{"roles": []string{"user", "admin"}},
+		"resource_access":    map[string]interface{}{"smart-service": map[string]interface{}{"roles": []string{"editor"}}},
+		"email":              "user@example.com",
+		"tenant":             map[string]interface{}{"id": "t1"},
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	check := func(t *testing.T, token Token) {
+		if token.GetUserId() != "user-id" || !token.IsAdmin() || token.Azp != "frontend" || token.PreferredUsername != "user" {
+			t.Error(token)
+		}
+		if !reflect.DeepEqual(token.Groups, []string{"/users", "/admins"}) {
+			t.Error(token.Groups)
+		}
+		if !token.ExpiresAt().Equal(exp) || token.Iat != exp.Add(-5*time.Minute).Unix() {
+			t.Error(token.ExpiresAt(), token.Iat)
+		}
+		if token.IsExpired(exp.Add(-time.Second)) || !token.IsExpired(exp) {
+			t.Error("IsExpired")
+		}
+		if !token.HasClientRole("smart-service", "editor") || token.HasClientRole("smart-service", "admin") || token.HasClientRole("other", "editor") {
+			t.Error(token.ResourceAccess)
+		}
+		if email, ok := token.Claim("email"); !ok || email != "user@example.com" {
+			t.Error(email)
+		}
+		if tenant, ok := token.Claim("tenant"); !ok || !reflect.DeepEqual(tenant, map[string]interface{}{"id": "t1"}) {
+			t.Error(tenant)
+		}
+		if _, ok := token.Claim("unknown"); ok {
+			t.Error("unexpected claim")
+		}
+	}
+
+	token, err := Parse("Bearer " + signed)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if token.Jwt() != "Bearer "+signed {
+		t.Error(token.Jwt())
+	}
+	t.Run("parse", func(t *testing.T) {
+		check(t, token)
+	})
+
+	t.Run("json round trip", func(t *testing.T) {
+		temp, err := json.Marshal(token)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var cached Token
+		err = json.Unmarshal(temp, &cached)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		check(t, cached)
+	})
+
+	t.Run("unexpected claim formats", func(t *testing.T) {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":             "user-id",
+			"groups":          "not-a-list",
+			"resource_access": []string{"foo"},
+		}).SignedString([]byte("secret"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		token, err := Parse(signed)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if token.GetUserId() != "user-id" || token.Groups != nil || token.IsExpired(time.Now()) || !token.ExpiresAt().IsZero() {
+			t.Error(token)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+)
+
+const (
+	TokenSourceClientSecret  = "client_secret"
+	TokenSourcePrivateKeyJwt = "private_key_jwt"
+	TokenSourceStatic        = "static"
+	TokenSourceFile          = "file"
+)
+
+// TokenSource provides the client token returned by Auth.Ensure.
+// Auth caches the returned token and calls Token again shortly before OpenidToken.ExpiresIn has passed;
+// current is the last token (empty on the first call) and may be used to refresh.
+type TokenSource interface {
+	Token(endpoint TokenEndpoint, current OpenidToken) (OpenidToken, error)
+}
+
+// ClientAuthenticator is implemented by TokenSources that authenticate the client at the token endpoint.
+// Auth.ExchangeUserToken is only supported for such sources.
+type ClientAuthenticator interface {
+	ClientAuthentication(endpoint TokenEndpoint) (url.Values, error)
+}
+
+// TokenEndpoint is the token endpoint of the realm, as resolved by Auth
+type TokenEndpoint interface {
+	Url() string
+	// Request posts the form values and returns the decoded token response; errors wrap ErrAuth
+	Request(values url.Values) (OpenidToken, error)
+}
+
+// NewTokenSource selects the TokenSource by config.AuthTokenSource (default TokenSourceClientSecret)
+func NewTokenSource(config configuration.Config) (TokenSource, error) {
+	switch config.AuthTokenSource {
+	case "", TokenSourceClientSecret:
+		return &ClientSecretSource{ClientId: config.AuthClientId, ClientSecret: config.AuthClientSecret}, nil
+	case TokenSourcePrivateKeyJwt:
+		return NewPrivateKeyJwtSource(config.AuthClientId, config.AuthClientKeyId, []byte(config.AuthClientPrivateKey))
+	case TokenSourceStatic:
+		return &StaticTokenSource{AccessToken: config.AuthStaticToken}, nil
+	case TokenSourceFile:
+		path := config.AuthTokenFile
+		if path == "" {
+			path = DefaultTokenFile
+		}
+		return &FileTokenSource{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown auth_token_source %q", ErrAuth, config.AuthTokenSource)
+	}
+}
+
+// errorSource is used if NewTokenSource fails in New, so that the error is returned by Ensure
+type errorSource struct {
+	err error
+}
+
+func (this errorSource) Token(TokenEndpoint, OpenidToken) (OpenidToken, error) {
+	return OpenidToken{}, this.err
+}
+
+type tokenEndpoint struct {
+	auth *Auth
+}
+
+func (this tokenEndpoint) Url() string {
+	return this.auth.discovery.Get().TokenEndpoint
+}
+
+func (this tokenEndpoint) Request(values url.Values) (token OpenidToken, err error) {
+	err = this.auth.requestOpenidToken(&token, values)
+	return token, err
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+)
+
+func TestTokenSources(t *testing.T) {
+	server := newTestServer(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server.SetClientPublicKey(&key.PublicKey)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	config := testConfig(server)
+	config.AuthClientSecret = ""
+
+	t.Run("private_key_jwt", func(t *testing.T) {
+		c := config
+		c.AuthTokenSource = TokenSourcePrivateKeyJwt
+		c.AuthClientPrivateKey = pemKey
+		a := New(c)
+		token, err := a.Ensure()
+		if err != nil || token.GetUserId() != server.ClientSubject() {
+			t.Error(err, token)
+			return
+		}
+		token, err = a.ExchangeUserToken("user")
+		if err != nil || token.GetUserId() != "user" {
+			t.Error(err, token)
+			return
+		}
+		requests := server.Requests()
+		last := requests[len(requests)-1]
+		if last.GrantType != authtest.TokenExchangeGrantType || last.Form["client_assertion"] == nil || last.Form["client_secret"] != nil {
+			t.Error(last)
+		}
+	})
+
+	t.Run("private_key_jwt with unknown key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		a := New(config).SetTokenSource(&PrivateKeyJwtSource{ClientId: "client", Key: other})
+		_, err = a.Ensure()
+		if !errors.Is(err, ErrInvalidClientCredentials) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid private key", func(t *testing.T) {
+		c := config
+		c.AuthTokenSource = TokenSourcePrivateKeyJwt
+		c.AuthClientPrivateKey = "foo"
+		_, err := New(c).Ensure()
+		if !errors.Is(err, ErrInvalidClientCredentials) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("static", func(t *testing.T) {
+		static, err := server.Mint("dev-user", time.Hour)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c := config
+		c.AuthTokenSource = TokenSourceStatic
+		c.AuthStaticToken = static
+		a := New(c)
+		token, err := a.Ensure()
+		if err != nil || token.GetUserId() != "dev-user" || token.Jwt() != static {
+			t.Error(err, token)
+			return
+		}
+		_, err = a.ExchangeUserToken("user")
+		if !errors.Is(err, ErrExchangeUnsupported) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired, err := server.Mint("dev-user", -time.Minute)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c := config
+		c.AuthTokenSource = TokenSourceStatic
+		c.AuthStaticToken = expired
+		_, err = New(c).Ensure()
+		if !errors.Is(err, ErrInvalidClientCredentials) || !errors.Is(err, ErrTokenExpired) {
+			t.Error(err)
+		}
+
+		path := filepath.Join(t.TempDir(), "token")
+		err = os.WriteFile(path, []byte(expired), 0600)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c.AuthTokenSource = TokenSourceFile
+		c.AuthTokenFile = path
+		_, err = New(c).Ensure()
+		if !errors.Is(err, ErrInvalidClientCredentials) || !errors.Is(err, ErrTokenExpired) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		now := time.Now()
+		TimeNow = func() time.Time {
+			return now
+		}
+		defer func() {
+			TimeNow = time.Now
+		}()
+
+		path := filepath.Join(t.TempDir(), "token")
+		first, err := server.Mint("system:serviceaccount:worker", time.Hour)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = os.WriteFile(path, []byte(first+"\n"), 0600)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c := config
+		c.AuthTokenSource = TokenSourceFile
+		c.AuthTokenFile = path
+		a := New(c)
+		token, err := a.Ensure()
+		if err != nil || token.Jwt() != first {
+			t.Error(err, token)
+			return
+		}
+
+		rotated, err := server.Mint("system:serviceaccount:worker", time.Hour)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = os.WriteFile(path, []byte(rotated), 0600)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		token, err = a.Ensure()
+		if err != nil || token.Jwt() != first {
+			t.Error("token should be cached", err)
+			return
+		}
+		now = now.Add(2 * time.Minute)
+		token, err = a.Ensure()
+		if err != nil || token.Jwt() != rotated {
+			t.Error("rotated token should be read", err)
+		}
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		c := config
+		c.AuthTokenSource = "foo"
+		_, err := New(c).Ensure()
+		if !errors.Is(err, ErrAuth) {
+			t.Error(err)
+		}
+	})
+}
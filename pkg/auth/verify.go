@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrMalformedToken = errors.New("malformed token")
+var ErrInvalidSignature = errors.New("invalid token signature")
+var ErrUnknownSigningKey = errors.New("unknown token signing key")
+var ErrTokenExpired = errors.New("token is expired")
+var ErrTokenNotValidYet = errors.New("token is not valid yet")
+var ErrInvalidIssuer = errors.New("invalid token issuer")
+var ErrInvalidAudience = errors.New("invalid token audience")
+var ErrJwksUnavailable = errors.New("unable to load jwks")
+
+// jwks are reloaded after this duration, or if a token references an unknown key id (at most once per jwksMinReloadInterval)
+const jwksMaxAge = time.Hour
+const jwksMinReloadInterval = 10 * time.Second
+
+// Verifier parses tokens like Parse, but checks the signature against the realm jwks and validates exp, nbf, iss and aud.
+// Inbound tokens (e.g. for admin apis, checked with Token.IsAdmin) should be parsed with a Verifier.
+// The lib itself has no inbound tokens: Auth only parses tokens it requested from the token endpoint
+// (including the user tokens passed to scripts by util.GetUserToken), so they are only verified with config.AuthVerifyTokens.
+type Verifier struct {
+	config     configuration.Config
+	httpClient *http.Client
+	discovery  *discovery
+	audience   string
+	flight     singleflight.Group
+	mux        sync.Mutex
+	keys       map[string]*rsa.PublicKey
+	loadedAt   time.Time
+}
+
+// NewVerifier uses config.AuthTokenIssuer (default: the discovered issuer of config.AuthRealm) as expected issuer
+// and config.AuthTokenAudience as expected audience (not checked if empty)
+func NewVerifier(config configuration.Config) *Verifier {
+	return newVerifier(config, newDiscovery(config))
+}
+
+func newVerifier(config configuration.Config, discovery *discovery) *Verifier {
+	return &Verifier{
+		config:     config,
+		httpClient: newMetadataHttpClient(),
+		discovery:  discovery,
+		audience:   config.AuthTokenAudience,
+	}
+}
+
+// SetHttpClient replaces the http.Client used to load the jwks and the openid configuration;
+// nil restores the default client with a timeout of 10s. A client without timeout is used as a copy with a timeout of 10s.
+func (this *Verifier) SetHttpClient(client *http.Client) *Verifier {
+	if client == nil {
+		client = newMetadataHttpClient()
+	}
+	client = withMetadataTimeout(client)
+	this.mux.Lock()
+	this.httpClient = client
+	this.mux.Unlock()
+	this.discovery.setHttpClient(client)
+	return this
+}
+
+// Parse returns a Token if the token is valid; errors wrap one of ErrMalformedToken, ErrInvalidSignature, ErrUnknownSigningKey,
+// ErrTokenExpired, ErrTokenNotValidYet, ErrInvalidIssuer, ErrInvalidAudience or ErrJwksUnavailable
+func (this *Verifier) Parse(token string) (result Token, err error) {
+	return this.parse(token, true)
+}
+
+func (this *Verifier) parse(token string, checkAudience bool) (result Token, err error) {
+	raw := token
+	if len(raw) > 7 && strings.ToLower(raw[:7]) == "bearer " {
+		raw = raw[7:]
+	}
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "RS384", "RS512"}, SkipClaimsValidation: true}
+	_, err = parser.ParseWithClaims(raw, claims, this.keyFunc)
+	if err != nil {
+		return result, mapValidationError(err)
+	}
+	now := TimeNow().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return result, ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now, false) {
+		return result, ErrTokenNotValidYet
+	}
+	if !claims.VerifyIssuer(this.discovery.Get().Issuer, true) {
+		return result, fmt.Errorf("%w: %v", ErrInvalidIssuer, claims["iss"])
+	}
+	if checkAudience && this.audience != "" && !claims.VerifyAudience(this.audience, true) {
+		return result, fmt.Errorf("%w: %v", ErrInvalidAudience, claims["aud"])
+	}
+	result, err = Parse(token)
+	if err != nil {
+		return result, fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	return result, nil
+}
+
+func mapValidationError(err error) error {
+	validationErr := &jwt.ValidationError{}
+	if !errors.As(err, &validationErr) {
+		return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	for _, known := range []error{ErrUnknownSigningKey, ErrJwksUnavailable} {
+		if errors.Is(validationErr.Inner, known) {
+			return validationErr.Inner
+		}
+	}
+	if validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+		return ErrInvalidSignature
+	}
+	return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+}
+
+func (this *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return this.getKey(kid)
+}
+
+// getKey returns the key of kid; the jwks are loaded without holding the lock and concurrent loads are deduplicated
+func (this *Verifier) getKey(kid string) (key *rsa.PublicKey, err error) {
+	now := TimeNow()
+	this.mux.Lock()
+	key, ok := this.keys[kid]
+	reload := this.keys == nil || now.Sub(this.loadedAt) > jwksMaxAge || (!ok && now.Sub(this.loadedAt) > jwksMinReloadInterval)
+	this.mux.Unlock()
+	if reload {
+		_, err, _ = this.flight.Do("jwks", func() (interface{}, error) {
+			return nil, this.loadKeys(now)
+		})
+		if err != nil {
+			return nil, err
+		}
+		this.mux.Lock()
+		key, ok = this.keys[kid]
+		this.mux.Unlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownSigningKey, kid)
+	}
+	return key, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (this *Verifier) loadKeys(now time.Time) error {
+	this.mux.Lock()
+	client := this.httpClient
+	this.mux.Unlock()
+	resp, err := client.Get(this.discovery.Get().JwksUri)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJwksUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%w: %v %v", ErrJwksUnavailable, resp.StatusCode, string(body))
+	}
+	set := jwks{}
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrJwksUnavailable, err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			this.config.GetLogger().Warn("unable to decode jwks key", "kid", key.Kid, "error", err)
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			this.config.GetLogger().Warn("unable to decode jwks key", "kid", key.Kid, "error", err)
+			continue
+		}
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.keys = keys
+	this.loadedAt = now
+	return nil
+}
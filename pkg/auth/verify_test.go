@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+)
+
+func TestVerifier(t *testing.T) {
+	server := newTestServer(t)
+	other := newTestServer(t)
+
+	config := testConfig(server)
+	verifier := NewVerifier(config)
+
+	valid, _ := server.Mint("user", time.Minute)
+	expired, _ := server.Mint("user", -time.Minute)
+	foreign, _ := other.Mint("user", time.Minute)
+
+	t.Run("valid", func(t *testing.T) {
+		token, err := verifier.Parse("Bearer " + valid)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if token.GetUserId() != "user" || token.Jwt() != "Bearer "+valid {
+			t.Error(token)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		_, err := verifier.Parse(expired)
+		if !errors.Is(err, ErrTokenExpired) {
+			t.Error(err)
+		}
+	})
+	t.Run("signature", func(t *testing.T) {
+		_, err := verifier.Parse(foreign)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Error(err)
+		}
+	})
+	t.Run("malformed", func(t *testing.T) {
+		_, err := verifier.Parse("foo.bar")
+		if !errors.Is(err, ErrMalformedToken) {
+			t.Error(err)
+		}
+	})
+	t.Run("issuer", func(t *testing.T) {
+		c := config
+		c.AuthTokenIssuer = "https://other"
+		_, err := NewVerifier(c).Parse(valid)
+		if !errors.Is(err, ErrInvalidIssuer) {
+			t.Error(err)
+		}
+	})
+	t.Run("issuer with trailing slash", func(t *testing.T) {
+		c := config
+		c.AuthTokenIssuer = server.Issuer() + "/"
+		_, err := NewVerifier(c).Parse(valid)
+		if err != nil {
+			t.Error(err)
+		}
+		c.AuthEndpoint = "http://localhost:1"
+		if issuer := newDiscovery(c).fallback().Issuer; issuer != server.Issuer() {
+			t.Error("fallback issuer should be trimmed", issuer)
+		}
+	})
+	t.Run("audience", func(t *testing.T) {
+		c := config
+		c.AuthTokenAudience = "account"
+		_, err := NewVerifier(c).Parse(valid)
+		if err != nil {
+			t.Error(err)
+		}
+		c.AuthTokenAudience = "other"
+		_, err = NewVerifier(c).Parse(valid)
+		if !errors.Is(err, ErrInvalidAudience) {
+			t.Error(err)
+		}
+	})
+	t.Run("jwks unavailable", func(t *testing.T) {
+		c := config
+		c.AuthEndpoint = "http://localhost:1"
+		_, err := NewVerifier(c).Parse(valid)
+		if !errors.Is(err, ErrJwksUnavailable) {
+			t.Error(err)
+		}
+	})
+	t.Run("auth with verification", func(t *testing.T) {
+		c := config
+		c.AuthVerifyTokens = true
+		c.AuthTokenAudience = "other" //only checked for inbound tokens
+		a := New(c)
+		token, err := a.Ensure()
+		if err != nil || token.GetUserId() != server.ClientSubject() {
+			t.Error(err, token)
+			return
+		}
+		_, err = a.ExchangeUserToken("user")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, err = a.Verify(valid)
+		if !errors.Is(err, ErrInvalidAudience) {
+			t.Error(err)
+		}
+	})
+}
+
+// run with -race
+func TestConcurrentVerify(t *testing.T) {
+	server := newTestServer(t)
+
+	transport := &blockingJwksTransport{release: make(chan struct{})}
+	verifier := NewVerifier(configuration.Config{AuthEndpoint: server.URL, LogLevel: "error"})
+	if verifier.httpClient.Timeout == 0 {
+		t.Error("default http client should have a timeout")
+	}
+	verifier.SetHttpClient(&http.Client{Transport: transport})
+	if verifier.httpClient.Timeout != metadataTimeout || verifier.discovery.httpClient.Timeout != metadataTimeout || verifier.httpClient.Transport != transport {
+		t.Error("clients without timeout should get the metadata timeout")
+	}
+	token, _ := server.Mint("user", time.Minute)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := verifier.Parse(token); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for transport.requests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the lock is not held during the request
+	locked := make(chan struct{})
+	go func() {
+		verifier.mux.Lock()
+		verifier.mux.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("jwks request should not hold the lock")
+	}
+
+	close(transport.release)
+	wg.Wait()
+	if count := transport.requests.Load(); count != 1 {
+		t.Error("concurrent jwks loads should be deduplicated", count)
+	}
+}
+
+// blockingJwksTransport blocks jwks requests until release is closed
+type blockingJwksTransport struct {
+	requests atomic.Int64
+	release  chan struct{}
+}
+
+func (this *blockingJwksTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, authtest.CertsPath) {
+		this.requests.Add(1)
+		<-this.release
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+)
+
+// Backend is a store shared by multiple worker instances (e.g. Redis), used by Typed behind its in-process entries.
+// Typed serializes values as json; implementations only store bytes.
+type Backend interface {
+	// Get returns ErrNotFound if key does not exist or is expired
+	Get(key string) (value []byte, err error)
+	// Set stores value for key; a ttl <= 0 means no expiration
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	// DeletePrefix deletes all keys starting with prefix
+	DeletePrefix(prefix string) error
+}
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// NewBackend creates the Backend selected by config.CacheBackend.
+// For "memory" (or "") it returns nil: Typed caches keep their values in-process only.
+// Shared backends need config.CacheEncryptionKey, because cached values contain tokens.
+func NewBackend(config configuration.Config) (Backend, error) {
+	switch config.CacheBackend {
+	case "", BackendMemory:
+		return nil, nil
+	case BackendRedis:
+		if config.CacheRedisAddr == "" {
+			return nil, errors.New("missing cache_redis_addr")
+		}
+		if config.CacheEncryptionKey == "" {
+			return nil, errors.New("missing cache_encryption_key")
+		}
+		key, err := base64.StdEncoding.DecodeString(config.CacheEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache_encryption_key: %w", err)
+		}
+		options := RedisOptions{Password: config.CacheRedisPassword, Db: config.CacheRedisDb}
+		if config.CacheRedisTls {
+			options.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		redis := NewRedisBackend(config.CacheRedisAddr, options)
+		encrypted, err := NewEncryptedBackend(redis, key)
+		if err != nil {
+			return nil, err
+		}
+		return encrypted, nil
+	default:
+		return nil, fmt.Errorf("unknown cache_backend %q", config.CacheBackend)
+	}
+}
+
+// backendEntry is the json representation of a typedEntry in a Backend; negative entries are not shared
+type backendEntry[V any] struct {
+	Value      V         `json:"value"`
+	Expires    time.Time `json:"expires,omitzero"`
+	StaleUntil time.Time `json:"stale_until,omitzero"`
+}
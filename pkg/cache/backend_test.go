@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"encoding/base64"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache/redistest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+)
+
+func TestTypedBackend(t *testing.T) {
+	server, err := redistest.NewServer("")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	config := configuration.Config{CacheBackend: BackendRedis, CacheRedisAddr: server.Addr, CacheEncryptionKey: key}
+	backend, err := NewBackend(config)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// two instances sharing the backend
+	first := NewTyped[string, *testValue](time.Minute, WithBackend(backend, "test/"))
+	second := NewTyped[string, *testValue](time.Minute, WithBackend(backend, "test/"))
+
+	loads := 0
+	loader := func() (*testValue, error) {
+		loads++
+		return &testValue{Name: "secret-token", Items: []int{loads}}, nil
+	}
+	value, err := first.Use("key", time.Minute, loader)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	shared, err := second.Use("key", time.Minute, loader)
+	if err != nil || loads != 1 || !reflect.DeepEqual(shared, value) {
+		t.Error(err, loads, shared)
+		return
+	}
+	if stats := first.Stats(); stats.Hits != 0 || stats.Misses != 1 || stats.BackendHits != 0 {
+		t.Error(stats)
+	}
+	if stats := second.Stats(); stats.Hits != 1 || stats.Misses != 0 || stats.BackendHits != 1 || stats.Entries != 1 {
+		t.Error(stats)
+	}
+
+	raw, ok := server.Get(0, "test/key")
+	if !ok || bytes.Contains(raw, []byte("secret-token")) {
+		t.Error("values should be stored encrypted", ok, string(raw))
+	}
+
+	second.Invalidate("key")
+	if _, ok := server.Get(0, "test/key"); ok {
+		t.Error("invalidate should delete the shared value")
+	}
+	if _, ok := first.Get("key"); !ok {
+		t.Error("in-process entries of other instances are kept until they expire")
+	}
+
+	t.Run("set and get", func(t *testing.T) {
+		first.Set("set", &testValue{Name: "set"}, NoExpiration)
+		value, ok := second.Get("set")
+		if !ok || value.Name != "set" {
+			t.Error(ok, value)
+		}
+	})
+
+	t.Run("invalidate prefix and flush", func(t *testing.T) {
+		other := NewTyped[string, *testValue](time.Minute, WithBackend(backend, "other/"))
+		other.Set("a/1", &testValue{}, NoExpiration)
+		first.Set("a/1", &testValue{}, NoExpiration)
+		first.Set("a/2", &testValue{}, NoExpiration)
+		first.Set("a*/3", &testValue{}, NoExpiration)
+		first.Set("b/1", &testValue{}, NoExpiration)
+		second.InvalidatePrefix("a/")
+		if keys := server.Keys(0); !reflect.DeepEqual(keys, []string{"other/a/1", "test/a*/3", "test/b/1", "test/set"}) {
+			t.Error(keys)
+		}
+		second.Flush()
+		if keys := server.Keys(0); !reflect.DeepEqual(keys, []string{"other/a/1"}) {
+			t.Error("flush should only delete the namespace of the cache", keys)
+		}
+		first.Set("set", &testValue{Name: "set"}, NoExpiration)
+	})
+
+	t.Run("values are bound to their key", func(t *testing.T) {
+		raw, _ := server.Get(0, "test/set")
+		redis := NewRedisBackend(server.Addr, RedisOptions{})
+		defer redis.Close()
+		if err := redis.Set("test/moved", raw, 0); err != nil {
+			t.Error(err)
+			return
+		}
+		c := NewTyped[string, *testValue](time.Minute, WithBackend(backend, "test/"))
+		if _, ok := c.Get("moved"); ok {
+			t.Error("moved value should not be decryptable")
+		}
+		if stats := c.Stats(); stats.BackendErrors != 1 {
+			t.Error(stats)
+		}
+	})
+
+	t.Run("unreachable backend", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		c := NewTyped[string, *testValue](time.Minute,
+			WithBackend(NewRedisBackend("localhost:1", RedisOptions{Timeout: time.Second}), "test/"),
+			WithLogger(slog.New(slog.NewTextHandler(logs, nil))))
+		value, err := c.Use("key", time.Minute, loader)
+		if err != nil || value == nil {
+			t.Error("backend errors should not fail the cache", err)
+		}
+		if stats := c.Stats(); stats.BackendErrors != 2 {
+			t.Error(stats)
+		}
+		if count := strings.Count(logs.String(), "cache backend error"); count != 2 {
+			t.Error("backend errors should be logged with the logger", logs.String())
+		}
+	})
+}
+
+func TestNewBackend(t *testing.T) {
+	backend, err := NewBackend(configuration.Config{})
+	if err != nil || backend != nil {
+		t.Error("the in-process store should be the default", err, backend)
+	}
+	_, err = NewBackend(configuration.Config{CacheBackend: BackendRedis, CacheRedisAddr: "localhost:6379"})
+	if err == nil {
+		t.Error("shared backends should require an encryption key")
+	}
+	_, err = NewBackend(configuration.Config{CacheBackend: BackendRedis, CacheRedisAddr: "localhost:6379", CacheEncryptionKey: base64.StdEncoding.EncodeToString([]byte("short"))})
+	if err == nil {
+		t.Error("expected invalid key length error")
+	}
+	_, err = NewBackend(configuration.Config{CacheBackend: "unknown"})
+	if err == nil {
+		t.Error("expected unknown backend error")
+	}
+	_, err = NewEncryptedBackend(nil, []byte("short"))
+	if err == nil {
+		t.Error("expected invalid key length error")
+	}
+}
@@ -20,11 +20,18 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 )
 
+// Cache stores json serialized values without a bound on the number of entries;
+// load errors are not cached and expired values are never served while they are reloaded.
+//
+// Deprecated: use Typed, which replaces Cache: it stores values natively, deduplicates concurrent loads,
+// evicts the least recently used entries when bounded with WithMaxEntries and supports
+// WithNegativeCaching and WithStaleWhileRevalidate.
 type Cache struct {
 	cache *cache.Cache
 }
@@ -36,6 +43,9 @@ type Item struct {
 
 var ErrNotFound = errors.New("key not found in cache")
 
+// NewCache creates an unbounded Cache.
+//
+// Deprecated: use NewTyped with WithMaxEntries.
 func NewCache(defaultExpirationInSeconds int) *Cache {
 	return &Cache{cache: cache.New(time.Duration(defaultExpirationInSeconds)*time.Second, time.Duration(defaultExpirationInSeconds)*time.Second)}
 }
@@ -62,6 +72,35 @@ func (this *Cache) set(key string, value []byte, expiration time.Duration) {
 	return
 }
 
+// Set stores value (json serialized) for key, replacing any existing item
+func (this *Cache) Set(key string, value interface{}, expiration time.Duration) error {
+	temp, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	this.set(key, temp, expiration)
+	return nil
+}
+
+// Invalidate removes the item of key, so that the next Use calls the getter
+func (this *Cache) Invalidate(key string) {
+	this.cache.Delete(key)
+}
+
+// InvalidatePrefix removes all items with a key starting with prefix
+func (this *Cache) InvalidatePrefix(prefix string) {
+	for key := range this.cache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			this.cache.Delete(key)
+		}
+	}
+}
+
+// Flush removes all items
+func (this *Cache) Flush() {
+	this.cache.Flush()
+}
+
 func (this *Cache) Use(key string, expiration time.Duration, getter func() (interface{}, error), result interface{}) (err error) {
 	value, err := this.get(key)
 	if err == nil {
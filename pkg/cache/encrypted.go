@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EncryptedBackend encrypts the values of another Backend with AES-GCM.
+// The key of an entry is authenticated with its value, so values can not be moved to other keys.
+type EncryptedBackend struct {
+	backend Backend
+	aead    cipher.AEAD
+}
+
+// NewEncryptedBackend wraps backend; key must have 16, 24 or 32 bytes (AES-128, AES-192 or AES-256)
+func NewEncryptedBackend(backend Backend, key []byte) (*EncryptedBackend, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedBackend{backend: backend, aead: aead}, nil
+}
+
+func (this *EncryptedBackend) Get(key string) (value []byte, err error) {
+	sealed, err := this.backend.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	size := this.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("unable to decrypt cache value: too short")
+	}
+	value, err = this.aead.Open(nil, sealed[:size], sealed[size:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt cache value: %w", err)
+	}
+	return value, nil
+}
+
+func (this *EncryptedBackend) Set(key string, value []byte, ttl time.Duration) error {
+	nonce := make([]byte, this.aead.NonceSize(), this.aead.NonceSize()+len(value)+this.aead.Overhead())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	return this.backend.Set(key, this.aead.Seal(nonce, nonce, value, []byte(key)), ttl)
+}
+
+func (this *EncryptedBackend) Delete(key string) error {
+	return this.backend.Delete(key)
+}
+
+func (this *EncryptedBackend) DeletePrefix(prefix string) error {
+	return this.backend.DeletePrefix(prefix)
+}
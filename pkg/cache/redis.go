@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RedisOptions struct {
+	Password     string        //sent with AUTH, if set
+	Db           int           //selected with SELECT, if not 0
+	Timeout      time.Duration //dial and command timeout; defaults to 5s
+	MaxIdleConns int           //defaults to 4
+	TLS          *tls.Config   //connections use TLS, if set; an empty ServerName is taken from the address
+}
+
+// RedisBackend is a Backend speaking the redis protocol (RESP), compatible with Redis, Valkey, KeyDB, ...
+// Connections are pooled; RedisBackend is safe for concurrent use.
+type RedisBackend struct {
+	addr    string
+	options RedisOptions
+	mux     sync.Mutex
+	idle    []*redisConn
+	closed  bool
+}
+
+// ErrClosed is returned by RedisBackend calls after Close
+var ErrClosed = errors.New("redis: backend closed")
+
+// limits of replies read by ReadReply; larger lengths are rejected before allocating
+const (
+	maxBulkLength  = 64 << 20
+	maxArrayLength = 1 << 20
+)
+
+// RedisError is an error reply of the server
+type RedisError string
+
+func (this RedisError) Error() string {
+	return "redis: " + string(this)
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisBackend creates a RedisBackend for addr (host:port); connections are opened on first use
+func NewRedisBackend(addr string, options RedisOptions) *RedisBackend {
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Second
+	}
+	if options.MaxIdleConns <= 0 {
+		options.MaxIdleConns = 4
+	}
+	return &RedisBackend{addr: addr, options: options}
+}
+
+func (this *RedisBackend) Get(key string) (value []byte, err error) {
+	reply, err := this.Do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, nil
+}
+
+func (this *RedisBackend) Set(key string, value []byte, ttl time.Duration) error {
+	if ttl > 0 {
+		_, err := this.Do("SET", key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+		return err
+	}
+	_, err := this.Do("SET", key, string(value))
+	return err
+}
+
+func (this *RedisBackend) Delete(key string) error {
+	_, err := this.Do("DEL", key)
+	return err
+}
+
+// DeletePrefix deletes all keys starting with prefix, found with SCAN (does not block the server like KEYS)
+func (this *RedisBackend) DeletePrefix(prefix string) error {
+	pattern := globEscaper.Replace(prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := this.Do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return fmt.Errorf("redis: unexpected SCAN reply %v", reply)
+		}
+		next, ok := parts[0].([]byte)
+		if !ok {
+			return fmt.Errorf("redis: unexpected SCAN cursor %v", parts[0])
+		}
+		keys, _ := parts[1].([]interface{})
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if key, ok := key.([]byte); ok {
+					args = append(args, string(key))
+				}
+			}
+			_, err = this.Do(args...)
+			if err != nil {
+				return err
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Close closes the idle connections; connections in use are closed when they are returned.
+// Following calls return ErrClosed.
+func (this *RedisBackend) Close() error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, conn := range this.idle {
+		_ = conn.conn.Close()
+	}
+	this.idle = nil
+	this.closed = true
+	return nil
+}
+
+// Do sends a command and returns its reply: nil, string (simple strings), int64, []byte (bulk strings) or []interface{} (arrays).
+// Error replies are returned as RedisError.
+func (this *RedisBackend) Do(args ...string) (reply interface{}, err error) {
+	conn, err := this.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err = conn.do(this.options.Timeout, args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		_ = conn.conn.Close()
+		return nil, err
+	}
+	this.put(conn)
+	return reply, err
+}
+
+func (this *RedisBackend) get() (*redisConn, error) {
+	this.mux.Lock()
+	if this.closed {
+		this.mux.Unlock()
+		return nil, ErrClosed
+	}
+	if count := len(this.idle); count > 0 {
+		conn := this.idle[count-1]
+		this.idle = this.idle[:count-1]
+		this.mux.Unlock()
+		return conn, nil
+	}
+	this.mux.Unlock()
+	netConn, err := this.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if this.options.Password != "" {
+		_, err = conn.do(this.options.Timeout, []string{"AUTH", this.options.Password})
+	}
+	if err == nil && this.options.Db != 0 {
+		_, err = conn.do(this.options.Timeout, []string{"SELECT", strconv.Itoa(this.options.Db)})
+	}
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (this *RedisBackend) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: this.options.Timeout}
+	if this.options.TLS != nil {
+		return tls.DialWithDialer(dialer, "tcp", this.addr, this.options.TLS)
+	}
+	return dialer.Dial("tcp", this.addr)
+}
+
+func (this *RedisBackend) put(conn *redisConn) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.closed || len(this.idle) >= this.options.MaxIdleConns {
+		_ = conn.conn.Close()
+		return
+	}
+	this.idle = append(this.idle, conn)
+}
+
+func (this *redisConn) do(timeout time.Duration, args []string) (interface{}, error) {
+	err := this.conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+	_, err = this.conn.Write(AppendCommand(nil, args...))
+	if err != nil {
+		return nil, err
+	}
+	return ReadReply(this.reader)
+}
+
+// AppendCommand appends args as RESP array of bulk strings to buf
+func AppendCommand(buf []byte, args ...string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// ReadReply reads one RESP value; see RedisBackend.Do for the result types
+func ReadReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length: %w", err)
+		}
+		if size == -1 {
+			return nil, nil
+		}
+		if size < 0 || size > maxBulkLength {
+			return nil, fmt.Errorf("redis: invalid bulk length %v", size)
+		}
+		value := make([]byte, size+2)
+		_, err = io.ReadFull(reader, value)
+		if err != nil {
+			return nil, err
+		}
+		if value[size] != '\r' || value[size+1] != '\n' {
+			return nil, errors.New("redis: bulk string not terminated by CRLF")
+		}
+		return value[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid array length: %w", err)
+		}
+		if count == -1 {
+			return nil, nil
+		}
+		if count < 0 || count > maxArrayLength {
+			return nil, fmt.Errorf("redis: invalid array length %v", count)
+		}
+		// elements are read one by one; the capacity does not trust the announced count
+		result := make([]interface{}, 0, min(count, 1024))
+		for i := 0; i < count; i++ {
+			element, err := ReadReply(reader)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, element)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: invalid line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
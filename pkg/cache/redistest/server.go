@@ -0,0 +1,452 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package redistest provides a local in-process server speaking the redis protocol (RESP) for tests of cache.RedisBackend.
+// Set configuration.Config.CacheRedisAddr to Server.Addr; NewTLSServer starts a server requiring TLS.
+// The server supports PING, AUTH, SELECT, GET, SET (with EX/PX), DEL, EXISTS, KEYS, SCAN and FLUSHDB.
+package redistest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	Addr string
+
+	listener    net.Listener
+	password    string
+	certificate *x509.Certificate
+	mux         sync.Mutex
+	dbs         map[int]map[string]entry
+	now         func() time.Time
+	commands    []string
+	conns       map[net.Conn]bool
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time //zero: no expiration
+}
+
+// NewServer starts a server on a random local port; if password is not empty, clients have to send AUTH
+func NewServer(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return start(listener, password, nil), nil
+}
+
+// NewTLSServer is like NewServer, but clients have to connect with TLS.
+// The server uses a self-signed certificate for 127.0.0.1 and localhost, returned by Certificate.
+func NewTLSServer(password string) (*Server, error) {
+	certificate, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		return nil, err
+	}
+	return start(listener, password, certificate.Leaf), nil
+}
+
+func start(listener net.Listener, password string, certificate *x509.Certificate) *Server {
+	server := &Server{
+		Addr:        listener.Addr().String(),
+		listener:    listener,
+		password:    password,
+		certificate: certificate,
+		dbs:         map[int]map[string]entry{},
+		now:         time.Now,
+		conns:       map[net.Conn]bool{},
+	}
+	server.wg.Add(1)
+	go server.accept()
+	return server
+}
+
+// Certificate returns the certificate of a server started with NewTLSServer (nil otherwise),
+// e.g. for the RootCAs of cache.RedisOptions.TLS
+func (this *Server) Certificate() *x509.Certificate {
+	return this.certificate
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redistest"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// Close stops the server and closes all client connections
+func (this *Server) Close() {
+	_ = this.listener.Close()
+	this.mux.Lock()
+	this.closed = true
+	for conn := range this.conns {
+		_ = conn.Close()
+	}
+	this.mux.Unlock()
+	this.wg.Wait()
+}
+
+// SetNow replaces the clock used for expirations
+func (this *Server) SetNow(now func() time.Time) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.now = now
+}
+
+// Get returns the raw value of key in db, e.g. to check that values are stored encrypted
+func (this *Server) Get(db int, key string) (value []byte, ok bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	e, ok := this.lookup(db, key)
+	return e.value, ok
+}
+
+// Keys returns the sorted keys of db
+func (this *Server) Keys(db int) (result []string) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.keys(db, "*")
+}
+
+// Commands returns the names of all received commands (e.g. "GET"), in order
+func (this *Server) Commands() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.commands)
+}
+
+func (this *Server) accept() {
+	defer this.wg.Done()
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		this.mux.Lock()
+		if this.closed {
+			this.mux.Unlock()
+			_ = conn.Close()
+			return
+		}
+		this.conns[conn] = true
+		this.mux.Unlock()
+		this.wg.Add(1)
+		go this.serve(conn)
+	}
+}
+
+func (this *Server) serve(conn net.Conn) {
+	defer this.wg.Done()
+	defer func() {
+		this.mux.Lock()
+		delete(this.conns, conn)
+		this.mux.Unlock()
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	authenticated := this.password == ""
+	db := 0
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				_, _ = conn.Write(errorReply("ERR " + err.Error()))
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToUpper(args[0])
+		var reply []byte
+		switch {
+		case name == "AUTH":
+			if len(args) == 2 && args[1] == this.password {
+				authenticated = true
+				reply = simpleReply("OK")
+			} else {
+				reply = errorReply("WRONGPASS invalid password")
+			}
+		case !authenticated:
+			reply = errorReply("NOAUTH Authentication required.")
+		case name == "SELECT":
+			if len(args) != 2 {
+				reply = wrongArgs(name)
+				break
+			}
+			db, err = strconv.Atoi(args[1])
+			if err != nil {
+				reply = errorReply("ERR invalid DB index")
+				break
+			}
+			reply = simpleReply("OK")
+		default:
+			reply = this.handle(db, name, args[1:])
+		}
+		_, err = conn.Write(reply)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (this *Server) handle(db int, name string, args []string) []byte {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.commands = append(this.commands, name)
+	if this.dbs[db] == nil {
+		this.dbs[db] = map[string]entry{}
+	}
+	switch name {
+	case "PING":
+		return simpleReply("PONG")
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		e, ok := this.lookup(db, args[0])
+		if !ok {
+			return []byte("$-1\r\n")
+		}
+		return bulkReply(string(e.value))
+	case "SET":
+		if len(args) != 2 && len(args) != 4 {
+			return wrongArgs(name)
+		}
+		e := entry{value: []byte(args[1])}
+		if len(args) == 4 {
+			amount, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil || amount <= 0 {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			switch strings.ToUpper(args[2]) {
+			case "EX":
+				e.expires = this.now().Add(time.Duration(amount) * time.Second)
+			case "PX":
+				e.expires = this.now().Add(time.Duration(amount) * time.Millisecond)
+			default:
+				return errorReply("ERR syntax error")
+			}
+		}
+		this.dbs[db][args[0]] = e
+		return simpleReply("OK")
+	case "DEL", "EXISTS":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		count := 0
+		for _, key := range args {
+			if _, ok := this.lookup(db, key); ok {
+				count++
+				if name == "DEL" {
+					delete(this.dbs[db], key)
+				}
+			}
+		}
+		return intReply(count)
+	case "KEYS":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return arrayReply(this.keys(db, args[0]))
+	case "SCAN":
+		// returns all matching keys at once; the cursor is always 0
+		pattern := "*"
+		for i := 1; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		return append([]byte("*2\r\n"+string(bulkReply("0"))), arrayReply(this.keys(db, pattern))...)
+	case "FLUSHDB":
+		this.dbs[db] = map[string]entry{}
+		return simpleReply("OK")
+	default:
+		return errorReply(fmt.Sprintf("ERR unknown command '%v'", name))
+	}
+}
+
+// lookup expects this.mux to be locked
+func (this *Server) lookup(db int, key string) (entry, bool) {
+	e, ok := this.dbs[db][key]
+	if ok && !e.expires.IsZero() && !this.now().Before(e.expires) {
+		delete(this.dbs[db], key)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+// keys expects this.mux to be locked
+func (this *Server) keys(db int, pattern string) (result []string) {
+	for key := range this.dbs[db] {
+		if _, ok := this.lookup(db, key); !ok {
+			continue
+		}
+		if match(pattern, key) {
+			result = append(result, key)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// match implements the glob patterns of KEYS and SCAN: '*', '?' and '\\' escapes ('*' also matches '/')
+func match(pattern string, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(key); i >= 0; i-- {
+				if match(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+func readCommand(reader *bufio.Reader) (args []string, err error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// inline command, e.g. from redis-cli or telnet
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, errors.New("Protocol error: invalid multibulk length")
+	}
+	for i := 0; i < count; i++ {
+		line, err = readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errors.New("Protocol error: expected '$'")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errors.New("Protocol error: invalid bulk length")
+		}
+		arg := make([]byte, size+2)
+		_, err = io.ReadFull(reader, arg)
+		if err != nil {
+			return nil, err
+		}
+		if string(arg[size:]) != "\r\n" {
+			return nil, errors.New("Protocol error: expected CRLF")
+		}
+		args = append(args, string(arg[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func simpleReply(value string) []byte {
+	return []byte("+" + value + "\r\n")
+}
+
+func errorReply(message string) []byte {
+	return []byte("-" + message + "\r\n")
+}
+
+func wrongArgs(name string) []byte {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%v' command", strings.ToLower(name)))
+}
+
+func intReply(value int) []byte {
+	return []byte(":" + strconv.Itoa(value) + "\r\n")
+}
+
+func bulkReply(value string) []byte {
+	return []byte("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n")
+}
+
+func arrayReply(values []string) []byte {
+	result := []byte("*" + strconv.Itoa(len(values)) + "\r\n")
+	for _, value := range values {
+		result = append(result, bulkReply(value)...)
+	}
+	return result
+}
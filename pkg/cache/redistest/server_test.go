@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redistest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache"
+)
+
+func TestRedisBackend(t *testing.T) {
+	server, err := NewServer("secret")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+	now := time.Now()
+	server.SetNow(func() time.Time { return now })
+
+	t.Run("wrong password", func(t *testing.T) {
+		backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{Password: "wrong"})
+		defer backend.Close()
+		var redisErr cache.RedisError
+		if _, err := backend.Get("key"); !errors.As(err, &redisErr) {
+			t.Error(err)
+		}
+	})
+
+	backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{Password: "secret", Db: 2})
+	defer backend.Close()
+
+	_, err = backend.Get("key")
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Error(err)
+		return
+	}
+	err = backend.Set("key", []byte("value\r\nwith newline"), time.Minute)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = backend.Set("other", []byte("value"), 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	value, err := backend.Get("key")
+	if err != nil || string(value) != "value\r\nwith newline" {
+		t.Error(err, string(value))
+		return
+	}
+	if keys := server.Keys(2); !reflect.DeepEqual(keys, []string{"key", "other"}) {
+		t.Error(keys)
+	}
+	if keys := server.Keys(0); len(keys) != 0 {
+		t.Error(keys)
+	}
+
+	reply, err := backend.Do("SCAN", "0", "MATCH", "k*")
+	if err != nil || !reflect.DeepEqual(reply, []interface{}{[]byte("0"), []interface{}{[]byte("key")}}) {
+		t.Error(err, reply)
+	}
+	_, err = backend.Do("UNKNOWN")
+	var redisErr cache.RedisError
+	if !errors.As(err, &redisErr) {
+		t.Error(err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err = backend.Get("key"); !errors.Is(err, cache.ErrNotFound) {
+		t.Error("expected expiration", err)
+	}
+	err = backend.Delete("other")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = backend.Get("other"); !errors.Is(err, cache.ErrNotFound) {
+		t.Error("expected deletion", err)
+	}
+
+	// run with -race
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := backend.Set("concurrent", []byte("value"), time.Minute); err != nil {
+				t.Error(err)
+			}
+			if value, err := backend.Get("concurrent"); err != nil || string(value) != "value" {
+				t.Error(err, string(value))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRedisBackendTLS(t *testing.T) {
+	server, err := NewTLSServer("secret")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{Password: "secret", TLS: &tls.Config{}})
+		defer backend.Close()
+		var certErr x509.UnknownAuthorityError
+		if _, err := backend.Get("key"); !errors.As(err, &certErr) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("plain connection", func(t *testing.T) {
+		backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{Password: "secret", Timeout: time.Second})
+		defer backend.Close()
+		if _, err := backend.Get("key"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{Password: "secret", TLS: &tls.Config{RootCAs: roots}})
+	defer backend.Close()
+	err = backend.Set("key", []byte("value"), time.Minute)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	value, err := backend.Get("key")
+	if err != nil || string(value) != "value" {
+		t.Error(err, string(value))
+	}
+}
+
+func TestRedisBackendClose(t *testing.T) {
+	server, err := NewServer("")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer server.Close()
+
+	backend := cache.NewRedisBackend(server.Addr, cache.RedisOptions{})
+	if err := backend.Set("key", []byte("value"), 0); err != nil {
+		t.Error(err)
+		return
+	}
+	_ = backend.Close()
+	count := len(server.Commands())
+	if _, err := backend.Get("key"); !errors.Is(err, cache.ErrClosed) {
+		t.Error(err)
+	}
+	if err := backend.Set("key", []byte("value"), 0); !errors.Is(err, cache.ErrClosed) {
+		t.Error(err)
+	}
+	if commands := server.Commands(); len(commands) != count {
+		t.Error("closed backend should not send commands", commands[count:])
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	reply, err := cache.ReadReply(bufio.NewReader(strings.NewReader("$5\r\nvalue\r\n")))
+	if err != nil || !reflect.DeepEqual(reply, []byte("value")) {
+		t.Error(err, reply)
+	}
+	_, err = cache.ReadReply(bufio.NewReader(strings.NewReader("$5\r\nvalueXY+OK\r\n")))
+	if err == nil {
+		t.Error("bulk strings without CRLF should be rejected")
+	}
+	for _, reply := range []string{"$9999999999\r\n", "$-2\r\n", "*9999999999\r\n", "*-5\r\n"} {
+		if _, err = cache.ReadReply(bufio.NewReader(strings.NewReader(reply))); err == nil {
+			t.Error("invalid lengths should be rejected", reply)
+		}
+	}
+	for _, reply := range []string{"$-1\r\n", "*-1\r\n"} {
+		if result, err := cache.ReadReply(bufio.NewReader(strings.NewReader(reply))); err != nil || result != nil {
+			t.Error("null replies should be nil", reply, err, result)
+		}
+	}
+}
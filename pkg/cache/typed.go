@@ -0,0 +1,595 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoExpiration may be used as ttl for entries that never expire
+const NoExpiration time.Duration = -1
+
+// DefaultExpiration may be used as ttl to use the default ttl of the cache
+const DefaultExpiration time.Duration = 0
+
+// Typed stores values of type V without serialization.
+// Concurrent loads of the same key are deduplicated: only one loader runs, the other callers wait for its result.
+// With WithMaxEntries, the least recently used entries are evicted when the cache is full.
+// With WithBackend, values are additionally shared (json serialized) with other instances through a Backend.
+// Typed is safe for concurrent use.
+type Typed[K comparable, V any] struct {
+	mux        sync.Mutex
+	entries    map[K]*list.Element //element values are *typedEntry[K, V]
+	lru        *list.List          //front: most recently used
+	loads      map[K]*typedLoad[V]
+	defaultTTL time.Duration
+	maxEntries int
+	stats      Stats
+	lastSweep  time.Time
+	now        func() time.Time
+	backend    Backend
+	namespace  string
+	logger     *slog.Logger
+}
+
+type typedEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	err        error     //set for negative entries (WithNegativeCaching)
+	expires    time.Time //zero for entries without expiration
+	staleUntil time.Time //the value may be served while it is revalidated until staleUntil (WithStaleWhileRevalidate)
+}
+
+const (
+	entryFresh = iota
+	entryStale
+	entryExpired
+)
+
+func (this *typedEntry[K, V]) state(now time.Time) int {
+	switch {
+	case this.expires.IsZero() || now.Before(this.expires):
+		return entryFresh
+	case now.Before(this.staleUntil):
+		return entryStale
+	default:
+		return entryExpired
+	}
+}
+
+type typedLoad[V any] struct {
+	done        chan struct{}
+	value       V
+	err         error
+	invalidated bool //set by Invalidate, InvalidatePrefix and Flush while the load runs; the result is returned but not stored
+	revalidate  bool //background load of a stale entry; the lookup is already counted as hit
+}
+
+// Option configures a Typed cache
+type Option func(*options)
+
+type options struct {
+	maxEntries int
+	backend    Backend
+	namespace  string
+	logger     *slog.Logger
+}
+
+// WithMaxEntries bounds the cache to maxEntries entries (least recently used are evicted first); 0 means unbounded
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithBackend shares the values of the cache through backend (e.g. Redis), with namespace as key prefix.
+// Entries are still kept in-process until they expire; loads check the backend before calling the loader,
+// loaded and set values are written to it. A nil backend is ignored. Negative entries are not shared.
+// Backend errors are logged and counted in Stats.BackendErrors, but never fail the cache.
+func WithBackend(backend Backend, namespace string) Option {
+	return func(o *options) {
+		o.backend = backend
+		o.namespace = namespace
+	}
+}
+
+// WithLogger sets the logger for backend errors; a nil logger (default) uses the log package
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// UseOption configures a single Use, UseWithExpirationInResult or Reload call
+type UseOption func(*useOptions)
+
+type useOptions struct {
+	negative []negativeRule
+	stale    time.Duration
+}
+
+type negativeRule struct {
+	ttl   time.Duration
+	match func(err error) bool
+}
+
+// WithNegativeCaching remembers load errors for ttl, so that following calls return the error without calling the loader.
+// If match is not nil, only matching errors are cached. Errors never replace a cached value.
+// May be used multiple times, e.g. with a short ttl for unavailable servers; the first matching option is used.
+func WithNegativeCaching(ttl time.Duration, match func(err error) bool) UseOption {
+	return func(o *useOptions) {
+		o.negative = append(o.negative, negativeRule{ttl: ttl, match: match})
+	}
+}
+
+// negativeTTL returns the ttl of the first negative caching rule matching err, or 0
+func (this useOptions) negativeTTL(err error) time.Duration {
+	for _, rule := range this.negative {
+		if rule.match == nil || rule.match(err) {
+			return rule.ttl
+		}
+	}
+	return 0
+}
+
+// WithStaleWhileRevalidate serves an expired value for up to stale after its expiration,
+// while the value is reloaded in the background
+func WithStaleWhileRevalidate(stale time.Duration) UseOption {
+	return func(o *useOptions) {
+		o.stale = stale
+	}
+}
+
+// Stats are the counters of a Typed cache, e.g. for metrics or logging
+type Stats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"` //entries removed because the cache was full
+	LoadErrors    uint64 `json:"load_errors"`
+	StaleHits     uint64 `json:"stale_hits"`     //hits served from a stale entry while it is revalidated; also counted as Hits
+	NegativeHits  uint64 `json:"negative_hits"`  //hits of cached errors; also counted as Hits
+	BackendHits   uint64 `json:"backend_hits"`   //values found in the Backend; also counted as Hits
+	BackendErrors uint64 `json:"backend_errors"` //failed Backend requests and undecodable Backend values
+	Entries       int    `json:"entries"`
+}
+
+func (this Stats) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("hits", this.Hits),
+		slog.Uint64("misses", this.Misses),
+		slog.Uint64("evictions", this.Evictions),
+		slog.Uint64("load_errors", this.LoadErrors),
+		slog.Uint64("stale_hits", this.StaleHits),
+		slog.Uint64("negative_hits", this.NegativeHits),
+		slog.Uint64("backend_hits", this.BackendHits),
+		slog.Uint64("backend_errors", this.BackendErrors),
+		slog.Int("entries", this.Entries),
+	)
+}
+
+// NewTyped creates a cache with defaultTTL for entries stored with DefaultExpiration
+func NewTyped[K comparable, V any](defaultTTL time.Duration, opts ...Option) *Typed[K, V] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Typed[K, V]{
+		entries:    map[K]*list.Element{},
+		lru:        list.New(),
+		loads:      map[K]*typedLoad[V]{},
+		defaultTTL: defaultTTL,
+		maxEntries: o.maxEntries,
+		now:        time.Now,
+		backend:    o.backend,
+		namespace:  o.namespace,
+		logger:     o.logger,
+	}
+}
+
+// Stats returns a snapshot of the counters
+func (this *Typed[K, V]) Stats() Stats {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result := this.stats
+	result.Entries = len(this.entries)
+	return result
+}
+
+// Get returns the value of key, if it exists and is not expired; negative and stale entries are ignored
+func (this *Typed[K, V]) Get(key K) (value V, ok bool) {
+	value, ok = this.getLocal(key)
+	if ok || this.backend == nil {
+		return value, ok
+	}
+	return this.getShared(key)
+}
+
+func (this *Typed[K, V]) getLocal(key K) (value V, ok bool) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	element, ok := this.entries[key]
+	if !ok {
+		if this.backend == nil {
+			this.stats.Misses++
+		}
+		return value, false
+	}
+	entry := element.Value.(*typedEntry[K, V])
+	state := entry.state(this.now())
+	if state == entryExpired {
+		this.remove(element)
+	}
+	if state != entryFresh || entry.err != nil {
+		if this.backend == nil {
+			this.stats.Misses++
+		}
+		return value, false
+	}
+	this.lru.MoveToFront(element)
+	this.stats.Hits++
+	return entry.value, true
+}
+
+// getShared reads key from the backend and stores a fresh value in-process
+func (this *Typed[K, V]) getShared(key K) (value V, ok bool) {
+	shared, ok := this.readBackend(key)
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if !ok {
+		this.stats.Misses++
+		return value, false
+	}
+	this.put(&typedEntry[K, V]{key: key, value: shared.Value, expires: shared.Expires, staleUntil: shared.StaleUntil})
+	this.stats.Hits++
+	this.stats.BackendHits++
+	return shared.Value, true
+}
+
+// remove expects this.mux to be locked
+func (this *Typed[K, V]) remove(element *list.Element) {
+	this.lru.Remove(element)
+	delete(this.entries, element.Value.(*typedEntry[K, V]).key)
+}
+
+// Set stores value for key, replacing any existing entry.
+// ttl may be DefaultExpiration or NoExpiration; other negative ttls remove the entry without storing value.
+func (this *Typed[K, V]) Set(key K, value V, ttl time.Duration) {
+	this.mux.Lock()
+	entry := this.set(key, value, nil, ttl, 0)
+	this.mux.Unlock()
+	this.writeBackend(entry)
+}
+
+// set returns nil and removes an existing entry, if ttl is negative (but not NoExpiration): the value is already expired;
+// expects this.mux to be locked
+func (this *Typed[K, V]) set(key K, value V, err error, ttl time.Duration, stale time.Duration) *typedEntry[K, V] {
+	if ttl == DefaultExpiration {
+		ttl = this.defaultTTL
+	}
+	if ttl < 0 && ttl != NoExpiration {
+		if element, ok := this.entries[key]; ok {
+			this.remove(element)
+		}
+		return nil
+	}
+	entry := &typedEntry[K, V]{key: key, value: value, err: err}
+	if ttl > 0 {
+		entry.expires = this.now().Add(ttl)
+		entry.staleUntil = entry.expires.Add(max(stale, 0))
+	}
+	this.put(entry)
+	return entry
+}
+
+// put stores entry and evicts entries if the cache is full; expects this.mux to be locked
+func (this *Typed[K, V]) put(entry *typedEntry[K, V]) {
+	now := this.now()
+	key := entry.key
+	if element, ok := this.entries[key]; ok {
+		element.Value = entry
+		this.lru.MoveToFront(element)
+	} else {
+		this.entries[key] = this.lru.PushFront(entry)
+	}
+	this.sweep(now)
+	for this.maxEntries > 0 && len(this.entries) > this.maxEntries {
+		this.remove(this.lru.Back())
+		this.stats.Evictions++
+	}
+}
+
+// sweep removes expired entries, at most once per default ttl; expects this.mux to be locked
+func (this *Typed[K, V]) sweep(now time.Time) {
+	interval := max(this.defaultTTL, time.Minute)
+	if now.Sub(this.lastSweep) < interval {
+		return
+	}
+	this.lastSweep = now
+	for _, element := range this.entries {
+		if element.Value.(*typedEntry[K, V]).state(now) == entryExpired {
+			this.remove(element)
+		}
+	}
+}
+
+// Invalidate removes the entry of key (also from the Backend).
+// A load of key that is running is not stored.
+func (this *Typed[K, V]) Invalidate(key K) {
+	this.mux.Lock()
+	if element, ok := this.entries[key]; ok {
+		this.remove(element)
+	}
+	if call, ok := this.loads[key]; ok {
+		call.invalidated = true
+	}
+	this.mux.Unlock()
+	if this.backend != nil {
+		this.countBackendError(this.backend.Delete(this.backendKey(key)))
+	}
+}
+
+// InvalidatePrefix removes all entries with a key starting with prefix (compared as fmt.Sprint(key)), also from the Backend.
+// Running loads of matching keys are not stored.
+func (this *Typed[K, V]) InvalidatePrefix(prefix string) {
+	this.mux.Lock()
+	for key, element := range this.entries {
+		if strings.HasPrefix(fmt.Sprint(key), prefix) {
+			this.remove(element)
+		}
+	}
+	for key, call := range this.loads {
+		if strings.HasPrefix(fmt.Sprint(key), prefix) {
+			call.invalidated = true
+		}
+	}
+	this.mux.Unlock()
+	if this.backend != nil {
+		this.countBackendError(this.backend.DeletePrefix(this.namespace + prefix))
+	}
+}
+
+// Flush removes all entries; with a Backend, all keys in the namespace of the cache are deleted.
+// Running loads are not stored.
+func (this *Typed[K, V]) Flush() {
+	this.mux.Lock()
+	this.entries = map[K]*list.Element{}
+	this.lru.Init()
+	for _, call := range this.loads {
+		call.invalidated = true
+	}
+	this.mux.Unlock()
+	if this.backend != nil {
+		this.countBackendError(this.backend.DeletePrefix(this.namespace))
+	}
+}
+
+func (this *Typed[K, V]) backendKey(key K) string {
+	return this.namespace + fmt.Sprint(key)
+}
+
+// readBackend returns the fresh backend entry of key
+func (this *Typed[K, V]) readBackend(key K) (result backendEntry[V], ok bool) {
+	data, err := this.backend.Get(this.backendKey(key))
+	if errors.Is(err, ErrNotFound) {
+		return result, false
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		this.countBackendError(err)
+		return result, false
+	}
+	if !result.Expires.IsZero() && !this.now().Before(result.Expires) {
+		return result, false
+	}
+	return result, true
+}
+
+// writeBackend stores entry in the backend, with the remaining (stale) lifetime as ttl
+func (this *Typed[K, V]) writeBackend(entry *typedEntry[K, V]) {
+	if this.backend == nil || entry == nil || entry.err != nil {
+		return
+	}
+	var ttl time.Duration
+	if !entry.expires.IsZero() {
+		ttl = entry.staleUntil.Sub(this.now())
+		if ttl <= 0 {
+			return
+		}
+	}
+	data, err := json.Marshal(backendEntry[V]{Value: entry.value, Expires: entry.expires, StaleUntil: entry.staleUntil})
+	if err == nil {
+		err = this.backend.Set(this.backendKey(entry.key), data, ttl)
+	}
+	this.countBackendError(err)
+}
+
+func (this *Typed[K, V]) countBackendError(err error) {
+	if err == nil {
+		return
+	}
+	if this.logger != nil {
+		this.logger.Warn("cache backend error", "error", err)
+	} else {
+		log.Println("WARNING: cache backend:", err)
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.stats.BackendErrors++
+}
+
+// Use returns the cached value of key or stores the result of loader with ttl
+func (this *Typed[K, V]) Use(key K, ttl time.Duration, loader func() (V, error), opts ...UseOption) (V, error) {
+	return this.UseWithExpirationInResult(key, func() (V, time.Duration, error) {
+		value, err := loader()
+		return value, ttl, err
+	}, opts...)
+}
+
+// UseWithExpirationInResult is like Use, but the loader decides the ttl of the entry (e.g. from a token expiration).
+// A negative ttl other than NoExpiration (e.g. of an almost expired token) returns the value without caching it.
+func (this *Typed[K, V]) UseWithExpirationInResult(key K, loader func() (V, time.Duration, error), opts ...UseOption) (V, error) {
+	o := useOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	this.mux.Lock()
+	if element, ok := this.entries[key]; ok {
+		entry := element.Value.(*typedEntry[K, V])
+		switch entry.state(this.now()) {
+		case entryFresh:
+			this.lru.MoveToFront(element)
+			this.stats.Hits++
+			if entry.err != nil {
+				this.stats.NegativeHits++
+			}
+			this.mux.Unlock()
+			return entry.value, entry.err
+		case entryStale:
+			this.lru.MoveToFront(element)
+			this.stats.Hits++
+			this.stats.StaleHits++
+			this.revalidate(key, loader, o)
+			this.mux.Unlock()
+			return entry.value, nil
+		default:
+			this.remove(element)
+		}
+	}
+	if this.backend == nil {
+		// with a backend, runLoad counts the lookup as hit or miss
+		this.stats.Misses++
+	}
+	return this.load(key, loader, o, true)
+}
+
+// Reload calls loader (or joins a running load of key) and stores the result, even if a valid entry exists.
+// On error, the existing entry is kept.
+func (this *Typed[K, V]) Reload(key K, loader func() (V, time.Duration, error), opts ...UseOption) (V, error) {
+	o := useOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	this.mux.Lock()
+	return this.load(key, loader, o, false)
+}
+
+// load expects this.mux to be locked and unlocks it; if shared is set, the Backend is checked before loader is called
+func (this *Typed[K, V]) load(key K, loader func() (V, time.Duration, error), o useOptions, shared bool) (V, error) {
+	if running, ok := this.loads[key]; ok {
+		this.mux.Unlock()
+		<-running.done
+		return running.value, running.err
+	}
+	call := &typedLoad[V]{done: make(chan struct{})}
+	this.loads[key] = call
+	this.mux.Unlock()
+	if caught := this.runLoad(key, call, loader, o, shared); caught != nil {
+		panic(caught)
+	}
+	return call.value, call.err
+}
+
+// revalidate starts a background load, if none is running; expects this.mux to be locked
+func (this *Typed[K, V]) revalidate(key K, loader func() (V, time.Duration, error), o useOptions) {
+	if _, ok := this.loads[key]; ok {
+		return
+	}
+	call := &typedLoad[V]{done: make(chan struct{}), revalidate: true}
+	this.loads[key] = call
+	go func() {
+		// panics are returned as call.err; there is no caller to propagate them to
+		_ = this.runLoad(key, call, loader, o, true)
+	}()
+}
+
+// runLoad calls loader (or reads a fresh value from the Backend, if shared is set), stores the result and finishes call;
+// a panic of loader is returned
+func (this *Typed[K, V]) runLoad(key K, call *typedLoad[V], loader func() (V, time.Duration, error), o useOptions, shared bool) (caught interface{}) {
+	if shared && this.backend != nil {
+		entry, ok := this.readBackend(key)
+		this.mux.Lock()
+		if !ok {
+			if !call.revalidate {
+				this.stats.Misses++
+			}
+			this.mux.Unlock()
+		} else {
+			delete(this.loads, key)
+			if !call.invalidated {
+				this.put(&typedEntry[K, V]{key: key, value: entry.Value, expires: entry.Expires, staleUntil: entry.StaleUntil})
+			}
+			if !call.revalidate {
+				this.stats.Hits++
+			}
+			this.stats.BackendHits++
+			this.mux.Unlock()
+			call.value = entry.Value
+			close(call.done)
+			return nil
+		}
+	}
+	var ttl time.Duration
+	func() {
+		defer func() {
+			caught = recover()
+			if caught != nil {
+				call.err = fmt.Errorf("cache loader panicked: %v", caught)
+			}
+		}()
+		call.value, ttl, call.err = loader()
+	}()
+	var stored *typedEntry[K, V]
+	this.mux.Lock()
+	delete(this.loads, key)
+	if call.err == nil {
+		if !call.invalidated {
+			stored = this.set(key, call.value, nil, ttl, o.stale)
+		}
+	} else {
+		this.stats.LoadErrors++
+		if ttl := o.negativeTTL(call.err); caught == nil && !call.invalidated && ttl > 0 && !this.hasValue(key) {
+			var zero V
+			this.set(key, zero, call.err, ttl, 0)
+		}
+	}
+	this.mux.Unlock()
+	if stored != nil {
+		this.writeBackend(stored)
+	}
+	close(call.done)
+	return caught
+}
+
+// hasValue returns true if key has a fresh or stale value; expects this.mux to be locked
+func (this *Typed[K, V]) hasValue(key K) bool {
+	element, ok := this.entries[key]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*typedEntry[K, V])
+	return entry.err == nil && entry.state(this.now()) != entryExpired
+}
@@ -0,0 +1,398 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	Name  string
+	Items []int
+}
+
+func TestTypedExpiration(t *testing.T) {
+	now := time.Now()
+	c := NewTyped[string, *testValue](time.Minute)
+	c.now = func() time.Time {
+		return now
+	}
+
+	value := &testValue{Name: "foo"}
+	c.Set("default", value, DefaultExpiration)
+	c.Set("short", value, time.Second)
+	c.Set("forever", value, NoExpiration)
+
+	if cached, ok := c.Get("default"); !ok || cached != value {
+		t.Error("values should be stored without serialization", cached, ok)
+	}
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("short"); ok {
+		t.Error("short should be expired")
+	}
+	if _, ok := c.Get("default"); !ok {
+		t.Error("default should not be expired")
+	}
+	now = now.Add(time.Hour)
+	if _, ok := c.Get("default"); ok {
+		t.Error("default should be expired")
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("forever should not be expired")
+	}
+	c.Invalidate("forever")
+	if _, ok := c.Get("forever"); ok {
+		t.Error("forever should be invalidated")
+	}
+}
+
+func TestTypedUse(t *testing.T) {
+	c := NewTyped[int, string](time.Minute)
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		_, err := c.Use(1, DefaultExpiration, func() (string, error) {
+			return "", errors.New("test")
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+		value, err := c.Use(1, DefaultExpiration, func() (string, error) {
+			return "foo", nil
+		})
+		if err != nil || value != "foo" {
+			t.Error(value, err)
+		}
+		value, err = c.Use(1, DefaultExpiration, func() (string, error) {
+			return "bar", nil
+		})
+		if err != nil || value != "foo" {
+			t.Error(value, err)
+		}
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		value, err := c.Reload(1, func() (string, time.Duration, error) {
+			return "bar", DefaultExpiration, nil
+		})
+		if err != nil || value != "bar" {
+			t.Error(value, err)
+		}
+		_, err = c.Reload(1, func() (string, time.Duration, error) {
+			return "", DefaultExpiration, errors.New("test")
+		})
+		if value, ok := c.Get(1); err == nil || !ok || value != "bar" {
+			t.Error("failed reload should keep the entry", value, err)
+		}
+	})
+
+	t.Run("negative ttl", func(t *testing.T) {
+		loads := 0
+		loader := func() (string, time.Duration, error) {
+			loads++
+			return "expired", -5 * time.Second, nil
+		}
+		for i := 0; i < 2; i++ {
+			value, err := c.UseWithExpirationInResult(3, loader)
+			if err != nil || value != "expired" {
+				t.Error(value, err)
+			}
+		}
+		if loads != 2 {
+			t.Error("values with negative ttl should not be cached", loads)
+		}
+		c.Set(3, "foo", NoExpiration)
+		c.Set(3, "bar", -time.Second)
+		if value, ok := c.Get(3); ok {
+			t.Error("Set with negative ttl should remove the entry", value)
+		}
+	})
+
+	t.Run("panicking loader", func(t *testing.T) {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			_, _ = c.Use(2, DefaultExpiration, func() (string, error) {
+				panic("test")
+			})
+		}()
+		value, err := c.Use(2, DefaultExpiration, func() (string, error) {
+			return "foo", nil
+		})
+		if err != nil || value != "foo" {
+			t.Error(value, err)
+		}
+	})
+}
+
+// run with -race
+func TestTypedConcurrentLoads(t *testing.T) {
+	c := NewTyped[string, int](time.Minute)
+	loads := atomic.Int64{}
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.Use("key", DefaultExpiration, func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil || value != 42 {
+				t.Error(value, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Error(loads.Load())
+	}
+}
+
+func TestTypedBounded(t *testing.T) {
+	c := NewTyped[string, int](time.Minute, WithMaxEntries(2))
+	c.Set("a", 1, DefaultExpiration)
+	c.Set("b", 2, DefaultExpiration)
+	c.Get("a") // b is now the least recently used entry
+	c.Set("c", 3, DefaultExpiration)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a should be cached")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c should be cached")
+	}
+	_, _ = c.Use("d", DefaultExpiration, func() (int, error) {
+		return 0, errors.New("test")
+	})
+	_, _ = c.Use("c", DefaultExpiration, func() (int, error) {
+		return 0, errors.New("not called")
+	})
+
+	expected := Stats{Hits: 4, Misses: 2, Evictions: 1, LoadErrors: 1, Entries: 2}
+	if stats := c.Stats(); stats != expected {
+		t.Errorf("%#v", stats)
+	}
+}
+
+func TestTypedNegativeCaching(t *testing.T) {
+	now := time.Now()
+	c := NewTyped[string, int](time.Minute)
+	c.now = func() time.Time {
+		return now
+	}
+	errNotFound := errors.New("not found")
+	loads := 0
+	use := func(err error) (int, error) {
+		return c.Use("key", DefaultExpiration, func() (int, error) {
+			loads++
+			return 0, err
+		}, WithNegativeCaching(time.Second, func(err error) bool {
+			return errors.Is(err, errNotFound)
+		}))
+	}
+
+	_, err := use(errors.New("temporary"))
+	if err == nil || loads != 1 {
+		t.Error(err, loads)
+	}
+	_, err = use(errNotFound)
+	if !errors.Is(err, errNotFound) || loads != 2 {
+		t.Error("unmatched errors should not be cached", err, loads)
+	}
+	_, err = use(nil)
+	if !errors.Is(err, errNotFound) || loads != 2 {
+		t.Error("matched errors should be cached", err, loads)
+	}
+	if _, ok := c.Get("key"); ok {
+		t.Error("Get should ignore negative entries")
+	}
+	now = now.Add(2 * time.Second)
+	value, err := use(nil)
+	if err != nil || value != 0 || loads != 3 {
+		t.Error(value, err, loads)
+	}
+	if stats := c.Stats(); stats.NegativeHits != 1 || stats.LoadErrors != 2 {
+		t.Errorf("%#v", stats)
+	}
+
+	t.Run("multiple rules", func(t *testing.T) {
+		errUnavailable := errors.New("unavailable")
+		loads := 0
+		use := func(key string, err error) (int, error) {
+			return c.Use(key, DefaultExpiration, func() (int, error) {
+				loads++
+				return 0, err
+			}, WithNegativeCaching(time.Minute, func(err error) bool {
+				return errors.Is(err, errNotFound)
+			}), WithNegativeCaching(time.Second, func(err error) bool {
+				return errors.Is(err, errUnavailable)
+			}))
+		}
+		_, _ = use("missing", errNotFound)
+		_, _ = use("unavailable", errUnavailable)
+		now = now.Add(2 * time.Second)
+		if _, err := use("missing", nil); !errors.Is(err, errNotFound) || loads != 2 {
+			t.Error("missing should use the ttl of its rule", err, loads)
+		}
+		if _, err := use("unavailable", nil); err != nil || loads != 3 {
+			t.Error("unavailable should use the ttl of its rule", err, loads)
+		}
+	})
+}
+
+// run with -race
+func TestTypedStaleWhileRevalidate(t *testing.T) {
+	mux := sync.Mutex{}
+	now := time.Now()
+	c := NewTyped[string, string](time.Minute)
+	c.now = func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mux.Lock()
+		defer mux.Unlock()
+		now = now.Add(d)
+	}
+
+	loads := atomic.Int64{}
+	release := make(chan struct{})
+	use := func(result string, err error) (string, error) {
+		return c.Use("key", 10*time.Second, func() (string, error) {
+			loads.Add(1)
+			if result == "second" {
+				<-release
+			}
+			return result, err
+		}, WithStaleWhileRevalidate(time.Minute))
+	}
+
+	value, err := use("first", nil)
+	if err != nil || value != "first" {
+		t.Error(value, err)
+		return
+	}
+	advance(20 * time.Second)
+	for i := 0; i < 3; i++ {
+		value, err = use("second", nil)
+		if err != nil || value != "first" {
+			t.Error("stale value should be served while revalidating", value, err)
+			return
+		}
+	}
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for value, _ := c.Get("key"); value != "second"; value, _ = c.Get("key") {
+		if time.Now().After(deadline) {
+			t.Error("missing revalidation")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if loads.Load() != 2 {
+		t.Error("concurrent revalidations should be deduplicated", loads.Load())
+	}
+
+	advance(20 * time.Second)
+	value, err = use("third", errors.New("test"))
+	if err != nil || value != "second" {
+		t.Error(value, err)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for c.Stats().LoadErrors != 1 {
+		if time.Now().After(deadline) {
+			t.Error("missing failed revalidation")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	value, err = use("fourth", nil)
+	if value != "second" || err != nil {
+		t.Error("failed revalidation should keep the stale value", value, err)
+	}
+	deadline = time.Now().Add(5 * time.Second)
+	for value, _ := c.Get("key"); value != "fourth"; value, _ = c.Get("key") {
+		if time.Now().After(deadline) {
+			t.Error("missing revalidation")
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	advance(2 * time.Minute)
+	value, err = use("fifth", nil)
+	if value != "fifth" || err != nil {
+		t.Error("values should not be served after the stale period", value, err)
+	}
+}
+
+func TestTypedInvalidation(t *testing.T) {
+	c := NewTyped[string, int](time.Minute)
+	c.Set("instances/1/user", 1, DefaultExpiration)
+	c.Set("instances/1/variables", 2, DefaultExpiration)
+	c.Set("instances/10/user", 3, DefaultExpiration)
+	c.Set("modules/1", 4, DefaultExpiration)
+
+	c.InvalidatePrefix("instances/1/")
+	for key, expected := range map[string]bool{"instances/1/user": false, "instances/1/variables": false, "instances/10/user": true, "modules/1": true} {
+		if _, ok := c.Get(key); ok != expected {
+			t.Error(key, ok)
+		}
+	}
+	c.Flush()
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Error(stats)
+	}
+
+	t.Run("running loads are not stored", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		result := make(chan int)
+		go func() {
+			value, _ := c.Use("instances/2/user", time.Minute, func() (int, error) {
+				close(started)
+				<-release
+				return 1, nil
+			})
+			result <- value
+		}()
+		<-started
+		c.InvalidatePrefix("instances/2/")
+		close(release)
+		if value := <-result; value != 1 {
+			t.Error("the caller should receive the loaded value", value)
+		}
+		if _, ok := c.Get("instances/2/user"); ok {
+			t.Error("the invalidated load should not be stored")
+		}
+	})
+}
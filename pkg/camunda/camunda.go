@@ -17,26 +17,25 @@
 package camunda
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"runtime/debug"
 	"sync"
 	"time"
 
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
 )
 
 func New(config configuration.Config, smartServiceRepo SmartServiceRepository, handler Handler) *Camunda {
+	return NewWithEngine(config, NewClient(config), smartServiceRepo, handler)
+}
+
+func NewWithEngine(config configuration.Config, engine Engine, smartServiceRepo SmartServiceRepository, handler Handler) *Camunda {
 	return &Camunda{
 		config:           config,
+		engine:           engine,
 		handler:          handler,
 		smartServiceRepo: smartServiceRepo,
 	}
@@ -46,8 +45,13 @@ func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config,
 	New(config, smartServiceRepo, handler).Start(ctx, wg)
 }
 
+func StartWithEngine(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, engine Engine, smartServiceRepo SmartServiceRepository, handler Handler) {
+	NewWithEngine(config, engine, smartServiceRepo, handler).Start(ctx, wg)
+}
+
 type Camunda struct {
 	config           configuration.Config
+	engine           Engine
 	handler          Handler
 	smartServiceRepo SmartServiceRepository
 }
@@ -82,7 +86,7 @@ func (this *Camunda) Start(ctx context.Context, wg *sync.WaitGroup) {
 }
 
 func (this *Camunda) executeNextTasks() (wait bool) {
-	tasks, err := this.getTasks()
+	tasks, err := this.engine.FetchAndLock()
 	if err != nil {
 		this.config.GetLogger().Error("error on ExecuteNextTasks getTask", "error", err)
 		return true
@@ -95,7 +99,9 @@ func (this *Camunda) executeNextTasks() (wait bool) {
 		if err != nil {
 			repoErr := this.smartServiceRepo.SendWorkerError(task, err)
 			if repoErr == nil {
-				_ = this.stopProcessInstance(task.ProcessInstanceId) //error is sent --> no more retries
+				_ = this.engine.DeleteProcessInstance(task.ProcessInstanceId) //error is sent --> no more retries
+			} else {
+				this.logSendWorkerError(task, repoErr)
 			}
 			//retry task after lock duration, if stop fails or repoErr != nil
 		} else {
@@ -106,7 +112,7 @@ func (this *Camunda) executeNextTasks() (wait bool) {
 				this.config.GetLogger().Error("error on executeNextTasks getTask", "error", err)
 				debug.PrintStack()
 			} else {
-				err = this.completeTask(task.Id, outputs)
+				err = this.engine.Complete(task.Id, outputs)
 				if err != nil {
 					this.config.GetLogger().Error("error on executeNextTasks getTask", "error", err, "stack", string(debug.Stack()))
 					this.handler.Undo(modules, err)
@@ -115,7 +121,9 @@ func (this *Camunda) executeNextTasks() (wait bool) {
 						//error is sent --> no more retries
 						//if it is a problem with the process we don't want any retries
 						//if it is a problem with the process-engine, the stop won't be successful and a future try may succeed
-						_ = this.stopProcessInstance(task.ProcessInstanceId)
+						_ = this.engine.DeleteProcessInstance(task.ProcessInstanceId)
+					} else {
+						this.logSendWorkerError(task, repoErr)
 					}
 				}
 			}
@@ -124,85 +132,10 @@ func (this *Camunda) executeNextTasks() (wait bool) {
 	return false
 }
 
-func (this *Camunda) getTasks() (tasks []model.CamundaExternalTask, err error) {
-	fetchRequest := model.CamundaFetchRequest{
-		WorkerId: this.config.CamundaWorkerId,
-		MaxTasks: this.config.CamundaFetchMaxTasks,
-		Topics:   []model.CamundaTopic{{LockDuration: this.config.CamundaLockDurationInMs, Name: this.config.CamundaWorkerTopic}},
-	}
-	client := http.Client{Timeout: 5 * time.Second}
-	b := new(bytes.Buffer)
-	err = json.NewEncoder(b).Encode(fetchRequest)
-	if err != nil {
-		return
-	}
-	endpoint := this.config.CamundaUrl + "/engine-rest/external-task/fetchAndLock"
-	resp, err := client.Post(endpoint, "application/json", b)
-	if err != nil {
-		return tasks, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		temp, err := ioutil.ReadAll(resp.Body)
-		err = errors.New(fmt.Sprintln(endpoint, resp.Status, resp.StatusCode, string(temp), err))
-		return tasks, err
-	}
-	err = json.NewDecoder(resp.Body).Decode(&tasks)
-	return
-}
-
-func (this *Camunda) completeTask(taskId string, outputs map[string]interface{}) (err error) {
-	this.config.GetLogger().Debug("complete task", "taskId", taskId, "outputs", outputs)
-	client := http.Client{Timeout: 5 * time.Second}
-
-	variables := map[string]model.CamundaVariable{}
-	for key, value := range outputs {
-		variables[key] = model.CamundaVariable{Value: value}
-	}
-
-	var completeRequest = model.CamundaCompleteRequest{WorkerId: this.config.CamundaWorkerId, Variables: variables}
-	b := new(bytes.Buffer)
-	err = json.NewEncoder(b).Encode(completeRequest)
-	if err != nil {
-		return
-	}
-	resp, err := client.Post(this.config.CamundaUrl+"/engine-rest/external-task/"+url.PathEscape(taskId)+"/complete", "application/json", b)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	pl, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode >= 300 {
-		this.config.GetLogger().Error("unable to complete task", "statuscode", resp.StatusCode, "response", string(pl))
-		return fmt.Errorf("unable to complete task: %v, %v", resp.StatusCode, string(pl))
+func (this *Camunda) logSendWorkerError(task model.CamundaExternalTask, err error) {
+	if errors.Is(err, auth.ErrAuth) {
+		this.config.GetLogger().Error("unable to authenticate to send worker error; retry task after lock duration", "taskId", task.Id, "error", err)
 	} else {
-		this.config.GetLogger().Debug("complete camunda task", "request", completeRequest, "response", string(pl))
-	}
-	return nil
-}
-
-func (this *Camunda) stopProcessInstance(id string) (err error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	request, err := http.NewRequest("DELETE", this.config.CamundaUrl+"/engine-rest/process-instance/"+url.PathEscape(id)+"?skipIoMappings=true", nil)
-	if err != nil {
-		return err
-	}
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode == http.StatusNotFound {
-		return nil
-	}
-	if resp.StatusCode == 200 || resp.StatusCode == 204 {
-		return nil
+		this.config.GetLogger().Error("unable to send worker error; retry task after lock duration", "taskId", task.Id, "error", err)
 	}
-	msg, _ := io.ReadAll(resp.Body)
-	err = errors.New("error on delete in engine for /engine-rest/process-instance/" + url.PathEscape(id) + ": " + resp.Status + " " + string(msg))
-	return err
 }
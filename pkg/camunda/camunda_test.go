@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package camunda
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+func TestMemoryEngine(t *testing.T) {
+	config := configuration.Config{CamundaLockDurationInMs: 60000, CamundaFetchMaxTasks: 10}
+	engine := NewMemoryEngine(config)
+	engine.AddTask(
+		model.CamundaExternalTask{Id: "t1", ProcessInstanceId: "p1", Variables: map[string]model.CamundaVariable{"fail": {Value: false}}},
+		model.CamundaExternalTask{Id: "t2", ProcessInstanceId: "p2", Variables: map[string]model.CamundaVariable{"fail": {Value: true}}},
+	)
+	repo := &SmartServiceRepositoryMock{}
+	handler := &HandlerMock{}
+	c := NewWithEngine(config, engine, repo, handler)
+
+	if wait := c.executeNextTasks(); wait {
+		t.Error("expected tasks")
+		return
+	}
+	if wait := c.executeNextTasks(); !wait {
+		t.Error("expected no more unlocked tasks")
+		return
+	}
+
+	completions := engine.Completions()
+	if len(completions) != 1 || completions[0].Task.Id != "t1" || !reflect.DeepEqual(completions[0].Outputs, map[string]interface{}{"out": "t1"}) {
+		t.Errorf("%#v", completions)
+	}
+	if deleted := engine.DeletedProcessInstances(); !reflect.DeepEqual(deleted, []string{"p2"}) {
+		t.Error(deleted)
+	}
+	if pending := engine.PendingTasks(); len(pending) != 0 {
+		t.Error(pending)
+	}
+	if !reflect.DeepEqual(repo.errors, []string{"t2"}) {
+		t.Error(repo.errors)
+	}
+	if len(repo.modules) != 1 {
+		t.Error(repo.modules)
+	}
+}
+
+func TestMemoryEngineLock(t *testing.T) {
+	engine := NewMemoryEngine(configuration.Config{CamundaLockDurationInMs: 60000})
+	engine.AddTask(model.CamundaExternalTask{Id: "t1"}, model.CamundaExternalTask{Id: "t2"})
+	tasks, _ := engine.FetchAndLock()
+	if len(tasks) != 0 {
+		t.Error("maxTasks 0 should fetch no tasks", tasks)
+		return
+	}
+
+	engine.config.CamundaFetchMaxTasks = 1
+	tasks, _ = engine.FetchAndLock()
+	if len(tasks) != 1 || tasks[0].Id != "t1" {
+		t.Error(tasks)
+		return
+	}
+	if err := engine.Complete("t2", nil); !errors.Is(err, ErrUnknownTask) {
+		t.Error(err)
+	}
+	if err := engine.Unlock("t1"); err != nil {
+		t.Error(err)
+	}
+	tasks, _ = engine.FetchAndLock()
+	if len(tasks) != 1 || tasks[0].Id != "t1" {
+		t.Error(tasks)
+		return
+	}
+	if err := engine.Failure("t1", "msg", "", 0, 0); err != nil {
+		t.Error(err)
+	}
+	tasks, _ = engine.FetchAndLock()
+	if len(tasks) != 1 || tasks[0].Id != "t2" {
+		t.Error(tasks)
+		return
+	}
+	if err := engine.BpmnError("t2", "code", "msg", nil); err != nil {
+		t.Error(err)
+	}
+	if len(engine.PendingTasks()) != 0 || len(engine.Failures()) != 1 || len(engine.BpmnErrors()) != 1 {
+		t.Error(engine.PendingTasks(), engine.Failures(), engine.BpmnErrors())
+	}
+}
+
+type SmartServiceRepositoryMock struct {
+	errors  []string
+	modules []model.Module
+}
+
+func (this *SmartServiceRepositoryMock) SendWorkerError(task model.CamundaExternalTask, err error) error {
+	this.errors = append(this.errors, task.Id)
+	return nil
+}
+
+func (this *SmartServiceRepositoryMock) SendWorkerModules(modules []model.Module) (result []model.SmartServiceModule, err error) {
+	this.modules = append(this.modules, modules...)
+	return result, nil
+}
+
+type HandlerMock struct{}
+
+func (this *HandlerMock) Do(task model.CamundaExternalTask) (modules []model.Module, outputs map[string]interface{}, err error) {
+	if task.Variables["fail"].Value == true {
+		return nil, nil, errors.New("test error")
+	}
+	return []model.Module{{}}, map[string]interface{}{"out": task.Id}, nil
+}
+
+func (this *HandlerMock) Undo(modules []model.Module, reason error) {}
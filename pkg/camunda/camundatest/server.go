@@ -0,0 +1,406 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package camundatest provides a fake Camunda 7 REST server for integration tests.
+// It serves the endpoints used by camunda.Client from an httptest.Server,
+// so camunda.Start can be tested exactly as deployed by setting configuration.Config.CamundaUrl to Server.URL.
+package camundatest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+type Server struct {
+	URL string
+
+	// Now is used to compute lock expiry; may be replaced to control time in tests
+	Now func() time.Time
+
+	server      *httptest.Server
+	mux         sync.Mutex
+	tasks       []*task
+	requests    []Request
+	completions []Completion
+	failures    []Failure
+	bpmnErrors  []BpmnError
+	deleted     []string
+	messages    []model.CamundaMessageRequest
+	injected    []*injectedError
+}
+
+// Request is a received request; the body is already read
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   []byte
+}
+
+type Completion struct {
+	Task    model.CamundaExternalTask
+	Request model.CamundaCompleteRequest
+}
+
+type Failure struct {
+	Task    model.CamundaExternalTask
+	Request model.CamundaFailureRequest
+}
+
+type BpmnError struct {
+	Task    model.CamundaExternalTask
+	Request model.CamundaBpmnErrorRequest
+}
+
+type task struct {
+	topic       string
+	task        model.CamundaExternalTask
+	workerId    string
+	lockedUntil time.Time
+}
+
+type injectedError struct {
+	method     string
+	pathPrefix string
+	statusCode int
+	remaining  int
+}
+
+func NewServer() *Server {
+	result := &Server{Now: time.Now}
+	result.server = httptest.NewServer(result.router())
+	result.URL = result.server.URL
+	return result
+}
+
+func (this *Server) Close() {
+	this.server.Close()
+}
+
+// AddTask queues tasks for the topic; they are returned by fetchAndLock in the order they are added
+func (this *Server) AddTask(topic string, tasks ...model.CamundaExternalTask) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, t := range tasks {
+		this.tasks = append(this.tasks, &task{topic: topic, task: t})
+	}
+}
+
+// ExpireLocks releases all locks, as if the lock duration has passed
+func (this *Server) ExpireLocks() {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, t := range this.tasks {
+		t.lockedUntil = time.Time{}
+		t.workerId = ""
+	}
+}
+
+// InjectError lets the next count requests with the method and a path starting with pathPrefix
+// (e.g. "/engine-rest/external-task/fetchAndLock") fail with statusCode
+func (this *Server) InjectError(method string, pathPrefix string, statusCode int, count int) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.injected = append(this.injected, &injectedError{method: method, pathPrefix: pathPrefix, statusCode: statusCode, remaining: count})
+}
+
+// PendingTasks returns the tasks of the topic that are not yet completed, failed without retries, bpmn-errored or deleted
+func (this *Server) PendingTasks(topic string) (result []model.CamundaExternalTask) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, t := range this.tasks {
+		if t.topic == topic {
+			result = append(result, t.task)
+		}
+	}
+	return result
+}
+
+func (this *Server) Requests() []Request {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.requests)
+}
+
+func (this *Server) Completions() []Completion {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.completions)
+}
+
+func (this *Server) Failures() []Failure {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.failures)
+}
+
+func (this *Server) BpmnErrors() []BpmnError {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.bpmnErrors)
+}
+
+func (this *Server) DeletedProcessInstances() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.deleted)
+}
+
+func (this *Server) Messages() []model.CamundaMessageRequest {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.messages)
+}
+
+// WaitFor polls condition until it returns true or the timeout is reached
+func (this *Server) WaitFor(timeout time.Duration, condition func(server *Server) bool) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if condition(this) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (this *Server) router() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /engine-rest/external-task/fetchAndLock", this.fetchAndLock)
+	mux.HandleFunc("POST /engine-rest/external-task/{id}/complete", this.complete)
+	mux.HandleFunc("POST /engine-rest/external-task/{id}/failure", this.failure)
+	mux.HandleFunc("POST /engine-rest/external-task/{id}/bpmnError", this.bpmnError)
+	mux.HandleFunc("POST /engine-rest/external-task/{id}/extendLock", this.extendLock)
+	mux.HandleFunc("POST /engine-rest/external-task/{id}/unlock", this.unlock)
+	mux.HandleFunc("DELETE /engine-rest/process-instance/{id}", this.deleteProcessInstance)
+	mux.HandleFunc("POST /engine-rest/message", this.message)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		request.Body = io.NopCloser(strings.NewReader(string(body)))
+		this.mux.Lock()
+		this.requests = append(this.requests, Request{Method: request.Method, Path: request.URL.Path, Query: request.URL.RawQuery, Body: body})
+		statusCode := this.popInjectedError(request)
+		this.mux.Unlock()
+		if statusCode != 0 {
+			http.Error(writer, "injected error", statusCode)
+			return
+		}
+		mux.ServeHTTP(writer, request)
+	})
+}
+
+func (this *Server) popInjectedError(request *http.Request) (statusCode int) {
+	for _, injected := range this.injected {
+		if injected.remaining > 0 && injected.method == request.Method && strings.HasPrefix(request.URL.Path, injected.pathPrefix) {
+			injected.remaining--
+			return injected.statusCode
+		}
+	}
+	return 0
+}
+
+func (this *Server) fetchAndLock(writer http.ResponseWriter, request *http.Request) {
+	fetchRequest := model.CamundaFetchRequest{}
+	err := json.NewDecoder(request.Body).Decode(&fetchRequest)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	now := this.Now()
+	result := []model.CamundaExternalTask{}
+	for _, t := range this.tasks {
+		// like camunda, maxTasks <= 0 fetches no tasks
+		if int64(len(result)) >= fetchRequest.MaxTasks {
+			break
+		}
+		if t.lockedUntil.After(now) {
+			continue
+		}
+		index := slices.IndexFunc(fetchRequest.Topics, func(topic model.CamundaTopic) bool {
+			return topic.Name == t.topic
+		})
+		if index < 0 {
+			continue
+		}
+		t.workerId = fetchRequest.WorkerId
+		t.lockedUntil = now.Add(time.Duration(fetchRequest.Topics[index].LockDuration) * time.Millisecond)
+		result = append(result, t.task)
+	}
+	writeJson(writer, result)
+}
+
+func (this *Server) complete(writer http.ResponseWriter, request *http.Request) {
+	completeRequest := model.CamundaCompleteRequest{}
+	if !decode(writer, request, &completeRequest) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	t, ok := this.removeLockedTask(writer, request.PathValue("id"), completeRequest.WorkerId)
+	if !ok {
+		return
+	}
+	this.completions = append(this.completions, Completion{Task: t.task, Request: completeRequest})
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) failure(writer http.ResponseWriter, request *http.Request) {
+	failureRequest := model.CamundaFailureRequest{}
+	if !decode(writer, request, &failureRequest) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index, ok := this.lockedTaskIndex(writer, request.PathValue("id"), failureRequest.WorkerId)
+	if !ok {
+		return
+	}
+	t := this.tasks[index]
+	this.failures = append(this.failures, Failure{Task: t.task, Request: failureRequest})
+	if failureRequest.Retries <= 0 {
+		this.tasks = slices.Delete(this.tasks, index, index+1)
+	} else {
+		t.task.Retries = failureRequest.Retries
+		t.task.Error = failureRequest.ErrorMessage
+		t.lockedUntil = this.Now().Add(time.Duration(failureRequest.RetryTimeout) * time.Millisecond)
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) bpmnError(writer http.ResponseWriter, request *http.Request) {
+	bpmnErrorRequest := model.CamundaBpmnErrorRequest{}
+	if !decode(writer, request, &bpmnErrorRequest) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	t, ok := this.removeLockedTask(writer, request.PathValue("id"), bpmnErrorRequest.WorkerId)
+	if !ok {
+		return
+	}
+	this.bpmnErrors = append(this.bpmnErrors, BpmnError{Task: t.task, Request: bpmnErrorRequest})
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) extendLock(writer http.ResponseWriter, request *http.Request) {
+	extendLockRequest := model.CamundaExtendLockRequest{}
+	if !decode(writer, request, &extendLockRequest) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index, ok := this.lockedTaskIndex(writer, request.PathValue("id"), extendLockRequest.WorkerId)
+	if !ok {
+		return
+	}
+	this.tasks[index].lockedUntil = this.Now().Add(time.Duration(extendLockRequest.NewDuration) * time.Millisecond)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) unlock(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index := slices.IndexFunc(this.tasks, func(t *task) bool {
+		return t.task.Id == request.PathValue("id")
+	})
+	if index < 0 {
+		http.Error(writer, "external task not found", http.StatusNotFound)
+		return
+	}
+	this.tasks[index].lockedUntil = time.Time{}
+	this.tasks[index].workerId = ""
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) deleteProcessInstance(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.deleted = append(this.deleted, id)
+	count := len(this.tasks)
+	this.tasks = slices.DeleteFunc(this.tasks, func(t *task) bool {
+		return t.task.ProcessInstanceId == id
+	})
+	if count == len(this.tasks) {
+		http.Error(writer, "process instance not found", http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (this *Server) message(writer http.ResponseWriter, request *http.Request) {
+	messageRequest := model.CamundaMessageRequest{}
+	if !decode(writer, request, &messageRequest) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.messages = append(this.messages, messageRequest)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// lockedTaskIndex expects this.mux to be locked; writes the error response if the task is unknown or locked by an other worker
+func (this *Server) lockedTaskIndex(writer http.ResponseWriter, id string, workerId string) (index int, ok bool) {
+	index = slices.IndexFunc(this.tasks, func(t *task) bool {
+		return t.task.Id == id
+	})
+	if index < 0 {
+		http.Error(writer, "external task not found", http.StatusNotFound)
+		return index, false
+	}
+	t := this.tasks[index]
+	if !t.lockedUntil.After(this.Now()) || t.workerId != workerId {
+		http.Error(writer, "external task is not locked by worker "+workerId, http.StatusInternalServerError)
+		return index, false
+	}
+	return index, true
+}
+
+func (this *Server) removeLockedTask(writer http.ResponseWriter, id string, workerId string) (t *task, ok bool) {
+	index, ok := this.lockedTaskIndex(writer, id, workerId)
+	if !ok {
+		return nil, false
+	}
+	t = this.tasks[index]
+	this.tasks = slices.Delete(this.tasks, index, index+1)
+	return t, true
+}
+
+func decode(writer http.ResponseWriter, request *http.Request, result interface{}) bool {
+	err := json.NewDecoder(request.Body).Decode(result)
+	if err != nil && err != io.EOF {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJson(writer http.ResponseWriter, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(value)
+}
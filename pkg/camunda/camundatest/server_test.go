@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package camundatest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+func TestCamundaStart(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddTask("test-topic",
+		model.CamundaExternalTask{Id: "t1", ProcessInstanceId: "p1"},
+		model.CamundaExternalTask{Id: "t2", ProcessInstanceId: "p2", Variables: map[string]model.CamundaVariable{"fail": {Value: true}}},
+	)
+	server.AddTask("other-topic", model.CamundaExternalTask{Id: "t3", ProcessInstanceId: "p3"})
+	server.InjectError(http.MethodPost, "/engine-rest/external-task/fetchAndLock", http.StatusInternalServerError, 1)
+
+	config := configuration.Config{
+		CamundaUrl:                    server.URL,
+		CamundaWorkerId:               "worker",
+		CamundaWorkerTopic:            "test-topic",
+		CamundaLockDurationInMs:       60000,
+		CamundaWorkerWaitDurationInMs: 10,
+		CamundaFetchMaxTasks:          10,
+		LogLevel:                      "error",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	camunda.Start(ctx, wg, config, &repoMock{}, &handlerMock{})
+
+	ok := server.WaitFor(5*time.Second, func(server *Server) bool {
+		return len(server.Completions()) == 1 && len(server.DeletedProcessInstances()) == 1
+	})
+	cancel()
+	wg.Wait()
+	if !ok {
+		t.Error("timeout", server.Completions(), server.DeletedProcessInstances())
+		return
+	}
+
+	completion := server.Completions()[0]
+	if completion.Task.Id != "t1" || completion.Request.WorkerId != "worker" || !reflect.DeepEqual(completion.Request.Variables, map[string]model.CamundaVariable{"out": {Value: "t1"}}) {
+		t.Errorf("%#v", completion)
+	}
+	if !reflect.DeepEqual(server.DeletedProcessInstances(), []string{"p2"}) {
+		t.Error(server.DeletedProcessInstances())
+	}
+	if len(server.PendingTasks("test-topic")) != 0 || len(server.PendingTasks("other-topic")) != 1 {
+		t.Error(server.PendingTasks("test-topic"), server.PendingTasks("other-topic"))
+	}
+	if requests := server.Requests(); len(requests) < 2 || requests[0].Path != "/engine-rest/external-task/fetchAndLock" {
+		t.Error(requests)
+	}
+}
+
+func TestLockExpiry(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	now := time.Now()
+	server.Now = func() time.Time {
+		return now
+	}
+	server.AddTask("topic", model.CamundaExternalTask{Id: "t1"})
+
+	config := configuration.Config{CamundaUrl: server.URL, CamundaWorkerId: "worker", CamundaWorkerTopic: "topic", CamundaLockDurationInMs: 1000, LogLevel: "error"}
+	tasks, err := camunda.NewClient(config).FetchAndLock()
+	if err != nil || len(tasks) != 0 {
+		t.Error("maxTasks 0 should fetch no tasks", err, tasks)
+		return
+	}
+
+	config.CamundaFetchMaxTasks = 1
+	client := camunda.NewClient(config)
+	tasks, err = client.FetchAndLock()
+	if err != nil || len(tasks) != 1 {
+		t.Error(err, tasks)
+		return
+	}
+	tasks, err = client.FetchAndLock()
+	if err != nil || len(tasks) != 0 {
+		t.Error(err, tasks)
+		return
+	}
+	now = now.Add(2 * time.Second)
+	if err = client.Complete("t1", nil); err == nil {
+		t.Error("expected error for expired lock")
+		return
+	}
+	tasks, err = client.FetchAndLock()
+	if err != nil || len(tasks) != 1 {
+		t.Error(err, tasks)
+		return
+	}
+	if err = client.ExtendLock("t1", 10*time.Second); err != nil {
+		t.Error(err)
+		return
+	}
+	now = now.Add(5 * time.Second)
+	if err = client.Complete("t1", map[string]interface{}{"foo": "bar"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = client.CorrelateMessage("event", nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if messages := server.Messages(); len(messages) != 1 || messages[0].MessageName != "event" || !messages[0].All {
+		t.Error(messages)
+	}
+}
+
+type repoMock struct{}
+
+func (this *repoMock) SendWorkerError(task model.CamundaExternalTask, err error) error {
+	return nil
+}
+
+func (this *repoMock) SendWorkerModules(modules []model.Module) (result []model.SmartServiceModule, err error) {
+	return result, nil
+}
+
+type handlerMock struct{}
+
+func (this *handlerMock) Do(task model.CamundaExternalTask) (modules []model.Module, outputs map[string]interface{}, err error) {
+	if task.Variables["fail"].Value == true {
+		return nil, nil, errors.New("test error")
+	}
+	return nil, map[string]interface{}{"out": task.Id}, nil
+}
+
+func (this *handlerMock) Undo(modules []model.Module, reason error) {}
@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package camunda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+// Client is the Engine implementation for the Camunda 7 REST api at configuration.Config.CamundaUrl
+type Client struct {
+	config     configuration.Config
+	httpClient *http.Client
+}
+
+// DefaultHttpTimeout is the timeout of the http.Client used by NewClient
+const DefaultHttpTimeout = 5 * time.Second
+
+func NewClient(config configuration.Config) *Client {
+	return &Client{config: config, httpClient: &http.Client{Timeout: DefaultHttpTimeout}}
+}
+
+// SetHttpClient replaces the http.Client used for requests to camunda; nil restores the default client
+func (this *Client) SetHttpClient(client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHttpTimeout}
+	}
+	this.httpClient = client
+	return this
+}
+
+func (this *Client) FetchAndLock() (tasks []model.CamundaExternalTask, err error) {
+	fetchRequest := model.CamundaFetchRequest{
+		WorkerId: this.config.CamundaWorkerId,
+		MaxTasks: this.config.CamundaFetchMaxTasks,
+		Topics:   []model.CamundaTopic{{LockDuration: this.config.CamundaLockDurationInMs, Name: this.config.CamundaWorkerTopic}},
+	}
+	b := new(bytes.Buffer)
+	err = json.NewEncoder(b).Encode(fetchRequest)
+	if err != nil {
+		return
+	}
+	endpoint := this.config.CamundaUrl + "/engine-rest/external-task/fetchAndLock"
+	resp, err := this.httpClient.Post(endpoint, "application/json", b)
+	if err != nil {
+		return tasks, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		temp, err := io.ReadAll(resp.Body)
+		err = errors.New(fmt.Sprintln(endpoint, resp.Status, resp.StatusCode, string(temp), err))
+		return tasks, err
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tasks)
+	return
+}
+
+func (this *Client) Complete(taskId string, outputs map[string]interface{}) (err error) {
+	this.config.GetLogger().Debug("complete task", "taskId", taskId, "outputs", outputs)
+
+	variables := map[string]model.CamundaVariable{}
+	for key, value := range outputs {
+		variables[key] = model.CamundaVariable{Value: value}
+	}
+
+	var completeRequest = model.CamundaCompleteRequest{WorkerId: this.config.CamundaWorkerId, Variables: variables}
+	b := new(bytes.Buffer)
+	err = json.NewEncoder(b).Encode(completeRequest)
+	if err != nil {
+		return
+	}
+	resp, err := this.httpClient.Post(this.config.CamundaUrl+"/engine-rest/external-task/"+url.PathEscape(taskId)+"/complete", "application/json", b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	pl, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		this.config.GetLogger().Error("unable to complete task", "statuscode", resp.StatusCode, "response", string(pl))
+		return fmt.Errorf("unable to complete task: %v, %v", resp.StatusCode, string(pl))
+	} else {
+		this.config.GetLogger().Debug("complete camunda task", "request", completeRequest, "response", string(pl))
+	}
+	return nil
+}
+
+func (this *Client) DeleteProcessInstance(id string) (err error) {
+	request, err := http.NewRequest("DELETE", this.config.CamundaUrl+"/engine-rest/process-instance/"+url.PathEscape(id)+"?skipIoMappings=true", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := this.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode == 200 || resp.StatusCode == 204 {
+		return nil
+	}
+	msg, _ := io.ReadAll(resp.Body)
+	err = errors.New("error on delete in engine for /engine-rest/process-instance/" + url.PathEscape(id) + ": " + resp.Status + " " + string(msg))
+	return err
+}
+
+func (this *Client) Failure(taskId string, errorMessage string, errorDetails string, retries int64, retryTimeout time.Duration) error {
+	return this.postTaskAction(taskId, "failure", model.CamundaFailureRequest{
+		WorkerId:     this.config.CamundaWorkerId,
+		ErrorMessage: errorMessage,
+		ErrorDetails: errorDetails,
+		Retries:      retries,
+		RetryTimeout: retryTimeout.Milliseconds(),
+	})
+}
+
+func (this *Client) BpmnError(taskId string, errorCode string, errorMessage string, variables map[string]interface{}) error {
+	camundaVariables := map[string]model.CamundaVariable{}
+	for key, value := range variables {
+		camundaVariables[key] = model.CamundaVariable{Value: value}
+	}
+	return this.postTaskAction(taskId, "bpmnError", model.CamundaBpmnErrorRequest{
+		WorkerId:     this.config.CamundaWorkerId,
+		ErrorCode:    errorCode,
+		ErrorMessage: errorMessage,
+		Variables:    camundaVariables,
+	})
+}
+
+func (this *Client) ExtendLock(taskId string, newDuration time.Duration) error {
+	return this.postTaskAction(taskId, "extendLock", model.CamundaExtendLockRequest{
+		WorkerId:    this.config.CamundaWorkerId,
+		NewDuration: newDuration.Milliseconds(),
+	})
+}
+
+func (this *Client) Unlock(taskId string) error {
+	return this.postTaskAction(taskId, "unlock", nil)
+}
+
+func (this *Client) postTaskAction(taskId string, action string, request interface{}) (err error) {
+	var body io.Reader
+	if request != nil {
+		b := new(bytes.Buffer)
+		err = json.NewEncoder(b).Encode(request)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	endpoint := this.config.CamundaUrl + "/engine-rest/external-task/" + url.PathEscape(taskId) + "/" + action
+	resp, err := this.httpClient.Post(endpoint, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	pl, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to %v task: %v, %v", action, resp.StatusCode, string(pl))
+	}
+	return nil
+}
+
+func (this *Client) CorrelateMessage(messageName string, variables map[string]model.CamundaVariable) (err error) {
+	if variables == nil {
+		variables = map[string]model.CamundaVariable{}
+	}
+	request, err := json.Marshal(model.CamundaMessageRequest{
+		MessageName:           messageName,
+		All:                   true,
+		ResultEnabled:         false,
+		ProcessVariablesLocal: variables,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := this.httpClient.Post(this.config.CamundaUrl+"/engine-rest/message", "application/json", bytes.NewBuffer(request))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		err = errors.New(string(response))
+	}
+	return err
+}
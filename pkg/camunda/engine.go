@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package camunda
+
+import (
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+// Engine is the process-engine api used by the worker;
+// Client implements it for the Camunda 7 REST api, MemoryEngine keeps everything in memory for tests
+type Engine interface {
+	FetchAndLock() (tasks []model.CamundaExternalTask, err error)
+	Complete(taskId string, outputs map[string]interface{}) error
+	Failure(taskId string, errorMessage string, errorDetails string, retries int64, retryTimeout time.Duration) error
+	BpmnError(taskId string, errorCode string, errorMessage string, variables map[string]interface{}) error
+	ExtendLock(taskId string, newDuration time.Duration) error
+	Unlock(taskId string) error
+	DeleteProcessInstance(id string) error
+	CorrelateMessage(messageName string, variables map[string]model.CamundaVariable) error
+}
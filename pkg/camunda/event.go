@@ -17,39 +17,12 @@
 package camunda
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"net/http"
+
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
-	"io"
-	"net/http"
 )
 
 func SendEventTrigger(config configuration.Config, eventId string, variables map[string]model.CamundaVariable) (err error) {
-	if variables == nil {
-		variables = map[string]model.CamundaVariable{}
-	}
-	request, err := json.Marshal(map[string]interface{}{
-		"messageName":           eventId,
-		"all":                   true,
-		"resultEnabled":         false,
-		"processVariablesLocal": variables,
-	})
-	if err != nil {
-		return err
-	}
-	resp, err := http.Post(config.CamundaUrl+"/engine-rest/message", "application/json", bytes.NewBuffer(request))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	response, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode >= 300 {
-		err = errors.New(string(response))
-	}
-	return err
+	return NewClient(config).SetHttpClient(http.DefaultClient).CorrelateMessage(eventId, variables)
 }
@@ -0,0 +1,247 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package camunda
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+var ErrUnknownTask = errors.New("unknown or not locked external task")
+
+// MemoryEngine is an in-memory Engine for tests;
+// tasks are seeded with AddTask and the results of the worker can be inspected with Completions, Failures, BpmnErrors, DeletedProcessInstances and Messages
+type MemoryEngine struct {
+	config      configuration.Config
+	mux         sync.Mutex
+	tasks       []*memoryTask
+	completions []MemoryCompletion
+	failures    []MemoryFailure
+	bpmnErrors  []MemoryBpmnError
+	deleted     []string
+	messages    []MemoryMessage
+}
+
+type memoryTask struct {
+	task        model.CamundaExternalTask
+	lockedUntil time.Time
+}
+
+type MemoryCompletion struct {
+	Task    model.CamundaExternalTask
+	Outputs map[string]interface{}
+}
+
+type MemoryFailure struct {
+	Task         model.CamundaExternalTask
+	ErrorMessage string
+	ErrorDetails string
+	Retries      int64
+	RetryTimeout time.Duration
+}
+
+type MemoryBpmnError struct {
+	Task         model.CamundaExternalTask
+	ErrorCode    string
+	ErrorMessage string
+	Variables    map[string]interface{}
+}
+
+type MemoryMessage struct {
+	MessageName string
+	Variables   map[string]model.CamundaVariable
+}
+
+// NewMemoryEngine uses config.CamundaFetchMaxTasks and config.CamundaLockDurationInMs like the REST client
+func NewMemoryEngine(config configuration.Config) *MemoryEngine {
+	return &MemoryEngine{config: config}
+}
+
+// AddTask queues tasks to be returned by FetchAndLock
+func (this *MemoryEngine) AddTask(tasks ...model.CamundaExternalTask) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, task := range tasks {
+		this.tasks = append(this.tasks, &memoryTask{task: task})
+	}
+}
+
+// PendingTasks returns the tasks that are neither completed nor removed by a failure without retries, a bpmn-error or a process instance deletion
+func (this *MemoryEngine) PendingTasks() (result []model.CamundaExternalTask) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	for _, task := range this.tasks {
+		result = append(result, task.task)
+	}
+	return result
+}
+
+func (this *MemoryEngine) Completions() []MemoryCompletion {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.completions)
+}
+
+func (this *MemoryEngine) Failures() []MemoryFailure {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.failures)
+}
+
+func (this *MemoryEngine) BpmnErrors() []MemoryBpmnError {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.bpmnErrors)
+}
+
+func (this *MemoryEngine) DeletedProcessInstances() []string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.deleted)
+}
+
+func (this *MemoryEngine) Messages() []MemoryMessage {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.messages)
+}
+
+func (this *MemoryEngine) FetchAndLock() (tasks []model.CamundaExternalTask, err error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	now := time.Now()
+	for _, task := range this.tasks {
+		// like camunda, maxTasks <= 0 fetches no tasks
+		if int64(len(tasks)) >= this.config.CamundaFetchMaxTasks {
+			break
+		}
+		if task.lockedUntil.After(now) {
+			continue
+		}
+		task.lockedUntil = now.Add(time.Duration(this.config.CamundaLockDurationInMs) * time.Millisecond)
+		tasks = append(tasks, task.task)
+	}
+	return tasks, nil
+}
+
+func (this *MemoryEngine) Complete(taskId string, outputs map[string]interface{}) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	task, err := this.removeLockedTask(taskId)
+	if err != nil {
+		return err
+	}
+	this.completions = append(this.completions, MemoryCompletion{Task: task, Outputs: outputs})
+	return nil
+}
+
+func (this *MemoryEngine) Failure(taskId string, errorMessage string, errorDetails string, retries int64, retryTimeout time.Duration) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index := this.lockedTaskIndex(taskId)
+	if index < 0 {
+		return ErrUnknownTask
+	}
+	task := this.tasks[index]
+	this.failures = append(this.failures, MemoryFailure{
+		Task:         task.task,
+		ErrorMessage: errorMessage,
+		ErrorDetails: errorDetails,
+		Retries:      retries,
+		RetryTimeout: retryTimeout,
+	})
+	if retries <= 0 {
+		this.tasks = slices.Delete(this.tasks, index, index+1)
+		return nil
+	}
+	task.task.Retries = retries
+	task.task.Error = errorMessage
+	task.lockedUntil = time.Now().Add(retryTimeout)
+	return nil
+}
+
+func (this *MemoryEngine) BpmnError(taskId string, errorCode string, errorMessage string, variables map[string]interface{}) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	task, err := this.removeLockedTask(taskId)
+	if err != nil {
+		return err
+	}
+	this.bpmnErrors = append(this.bpmnErrors, MemoryBpmnError{Task: task, ErrorCode: errorCode, ErrorMessage: errorMessage, Variables: variables})
+	return nil
+}
+
+func (this *MemoryEngine) ExtendLock(taskId string, newDuration time.Duration) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index := this.lockedTaskIndex(taskId)
+	if index < 0 {
+		return ErrUnknownTask
+	}
+	this.tasks[index].lockedUntil = time.Now().Add(newDuration)
+	return nil
+}
+
+func (this *MemoryEngine) Unlock(taskId string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index := this.lockedTaskIndex(taskId)
+	if index < 0 {
+		return ErrUnknownTask
+	}
+	this.tasks[index].lockedUntil = time.Time{}
+	return nil
+}
+
+func (this *MemoryEngine) DeleteProcessInstance(id string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.tasks = slices.DeleteFunc(this.tasks, func(task *memoryTask) bool {
+		return task.task.ProcessInstanceId == id
+	})
+	this.deleted = append(this.deleted, id)
+	return nil
+}
+
+func (this *MemoryEngine) CorrelateMessage(messageName string, variables map[string]model.CamundaVariable) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.messages = append(this.messages, MemoryMessage{MessageName: messageName, Variables: variables})
+	return nil
+}
+
+func (this *MemoryEngine) lockedTaskIndex(taskId string) int {
+	now := time.Now()
+	return slices.IndexFunc(this.tasks, func(task *memoryTask) bool {
+		return task.task.Id == taskId && task.lockedUntil.After(now)
+	})
+}
+
+func (this *MemoryEngine) removeLockedTask(taskId string) (task model.CamundaExternalTask, err error) {
+	index := this.lockedTaskIndex(taskId)
+	if index < 0 {
+		return task, ErrUnknownTask
+	}
+	task = this.tasks[index].task
+	this.tasks = slices.Delete(this.tasks, index, index+1)
+	return task, nil
+}
@@ -18,13 +18,13 @@ package configuration
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
-	"reflect"
 	"regexp"
 	"runtime/debug"
-	"strconv"
 	"strings"
 	"time"
 
@@ -32,20 +32,37 @@ import (
 )
 
 type Config struct {
-	DeviceRepositoryUrl                  string `json:"device_repository_url"`
-	SmartServiceRepositoryUrl            string `json:"smart_service_repository_url"`
-	CamundaUrl                           string `json:"camunda_url" config:"secret"`
+	DeviceRepositoryUrl                  string `json:"device_repository_url" validate:"url"`
+	SmartServiceRepositoryUrl            string `json:"smart_service_repository_url" validate:"required,url"`
+	CamundaUrl                           string `json:"camunda_url" config:"secret" validate:"required,url"`
 	CamundaWorkerId                      string `json:"camunda_worker_id"`
-	CamundaWorkerTopic                   string `json:"camunda_worker_topic"`
-	CamundaLockDurationInMs              int64  `json:"camunda_lock_duration_in_ms"`
-	CamundaWorkerWaitDurationInMs        int64  `json:"camunda_worker_wait_duration_in_ms"`
-	CamundaFetchMaxTasks                 int64  `json:"camunda_fetch_max_tasks"`
-	AuthEndpoint                         string `json:"auth_endpoint"`
+	CamundaWorkerTopic                   string `json:"camunda_worker_topic" validate:"required"`
+	CamundaLockDurationInMs              int64  `json:"camunda_lock_duration_in_ms" validate:"required,min=1" default:"60000"`
+	CamundaWorkerWaitDurationInMs        int64  `json:"camunda_worker_wait_duration_in_ms" default:"1000"`
+	CamundaFetchMaxTasks                 int64  `json:"camunda_fetch_max_tasks" validate:"required,min=1" default:"1"`
+	AuthEndpoint                         string `json:"auth_endpoint" validate:"url"`
 	AuthClientId                         string `json:"auth_client_id" config:"secret"`
 	AuthClientSecret                     string `json:"auth_client_secret" config:"secret"`
-	TokenCacheDefaultExpirationInSeconds int    `json:"token_cache_default_expiration_in_seconds"`
+	TokenCacheDefaultExpirationInSeconds int    `json:"token_cache_default_expiration_in_seconds" validate:"min=0" default:"60"`
+	TokenCacheMaxEntries                 int    `json:"token_cache_max_entries" validate:"min=0"`                                                             //maximum number of cached user tokens (least recently used are evicted); 0 means unbounded
+	AuthTokenSource                      string `json:"auth_token_source" validate:"oneof=client_secret private_key_jwt static file" default:"client_secret"` //"client_secret" (default), "private_key_jwt", "static" or "file"; see auth.NewTokenSource
+	AuthClientPrivateKey                 string `json:"auth_client_private_key" config:"secret"`                                                              //PEM encoded RSA key for "private_key_jwt"
+	AuthClientKeyId                      string `json:"auth_client_key_id"`                                                                                   //optional kid of client assertions for "private_key_jwt"
+	AuthStaticToken                      string `json:"auth_static_token" config:"secret"`                                                                    //token for "static"
+	AuthTokenFile                        string `json:"auth_token_file"`                                                                                      //token file for "file"; defaults to the kubernetes service account token
+	AuthRealm                            string `json:"auth_realm" default:"master"`                                                                          //realm below AuthEndpoint, used for openid discovery
+	AuthVerifyTokens                     bool   `json:"auth_verify_tokens"`                                                                                   //verify signature and claims of tokens received by auth.Auth
+	AuthTokenIssuer                      string `json:"auth_token_issuer" validate:"url"`                                                                     //issuer used for openid discovery and expected iss claim; discovered below AuthEndpoint if empty
+	AuthTokenAudience                    string `json:"auth_token_audience"`                                                                                  //expected aud claim of inbound tokens parsed by auth.Verifier; not checked if empty
+	AuthBackgroundRefresh                bool   `json:"auth_background_refresh"`                                                                              //renew the client token and frequently used user tokens before they expire (see auth.Auth.StartRefresher)
+	CacheBackend                         string `json:"cache_backend" validate:"oneof=memory redis" default:"memory"`                                         //"memory" (default) or "redis"; see cache.NewBackend
+	CacheRedisAddr                       string `json:"cache_redis_addr"`                                                                                     //host:port of the redis server for "redis"
+	CacheRedisPassword                   string `json:"cache_redis_password" config:"secret"`
+	CacheRedisDb                         int    `json:"cache_redis_db" validate:"min=0"`
+	CacheRedisTls                        bool   `json:"cache_redis_tls"`                      //connect to cache_redis_addr with TLS, verified with the system root CAs
+	CacheEncryptionKey                   string `json:"cache_encryption_key" config:"secret"` //base64 encoded AES key (16, 24 or 32 bytes) for values in shared cache backends
 
-	LogLevel string       `json:"log_level"`
+	LogLevel string       `json:"log_level" validate:"oneof=debug info warn error" default:"info"`
 	logger   *slog.Logger `json:"-"`
 }
 
@@ -53,20 +70,45 @@ func LoadLibConfig(location string) (config Config, err error) {
 	return Load[Config](location)
 }
 
-// loads config from json in location and used environment variables (e.g KafkaUrl --> KAFKA_URL)
+// loads config from (in this order) `default` struct tags, json in location and used environment variables
+// (e.g KafkaUrl --> KAFKA_URL, Kafka.Url --> KAFKA_URL); the result is checked with Validate (`validate` struct tags).
+// the json file is optional: if location is empty or does not exist, only defaults and environment variables are used
 func Load[T any](location string) (config T, err error) {
-	file, err := os.Open(location)
+	err = applyDefaults(&config)
+	if err != nil {
+		return config, err
+	}
+	err = loadFile(location, &config)
+	if err != nil {
+		return config, err
+	}
+	err = handleEnvironmentVars(&config)
 	if err != nil {
 		return config, err
 	}
-	err = json.NewDecoder(file).Decode(&config)
+	err = Validate(config)
 	if err != nil {
 		return config, err
 	}
-	handleEnvironmentVars(&config)
 	return config, nil
 }
 
+func loadFile(location string, config interface{}) error {
+	if location == "" {
+		return nil
+	}
+	file, err := os.Open(location)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("config file not found, use defaults and environment variables: ", location)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(config)
+}
+
 var camel = regexp.MustCompile("(^[^A-Z]*|[A-Z]*)([A-Z][^A-Z]+|$)")
 
 func fieldNameToEnvName(s string) string {
@@ -82,53 +124,9 @@ func fieldNameToEnvName(s string) string {
 	return strings.ToUpper(strings.Join(a, "_"))
 }
 
-// preparations for docker
-func handleEnvironmentVars[T any](config *T) {
-	configValue := reflect.Indirect(reflect.ValueOf(config))
-	configType := configValue.Type()
-	for index := 0; index < configType.NumField(); index++ {
-		fieldName := configType.Field(index).Name
-		fieldConfig := configType.Field(index).Tag.Get("config")
-		envName := fieldNameToEnvName(fieldName)
-		envValue := os.Getenv(envName)
-		if envValue != "" {
-			if !strings.Contains(fieldConfig, "secret") {
-				fmt.Println("use environment variable: ", envName, " = ", envValue)
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.Int64 || configValue.FieldByName(fieldName).Kind() == reflect.Int {
-				i, _ := strconv.ParseInt(envValue, 10, 64)
-				configValue.FieldByName(fieldName).SetInt(i)
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.String {
-				configValue.FieldByName(fieldName).SetString(envValue)
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.Bool {
-				b, _ := strconv.ParseBool(envValue)
-				configValue.FieldByName(fieldName).SetBool(b)
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.Float64 {
-				f, _ := strconv.ParseFloat(envValue, 64)
-				configValue.FieldByName(fieldName).SetFloat(f)
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.Slice {
-				val := []string{}
-				for _, element := range strings.Split(envValue, ",") {
-					val = append(val, strings.TrimSpace(element))
-				}
-				configValue.FieldByName(fieldName).Set(reflect.ValueOf(val))
-			}
-			if configValue.FieldByName(fieldName).Kind() == reflect.Map {
-				value := map[string]string{}
-				for _, element := range strings.Split(envValue, ",") {
-					keyVal := strings.Split(element, ":")
-					key := strings.TrimSpace(keyVal[0])
-					val := strings.TrimSpace(keyVal[1])
-					value[key] = val
-				}
-				configValue.FieldByName(fieldName).Set(reflect.ValueOf(value))
-			}
-		}
-	}
+// SetLogger replaces the logger returned by GetLogger; copies of the config made afterward share it
+func (this *Config) SetLogger(logger *slog.Logger) {
+	this.logger = logger
 }
 
 func (this *Config) GetLogger() *slog.Logger {
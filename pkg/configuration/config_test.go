@@ -0,0 +1,409 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package configuration
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	location := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(location, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location
+}
+
+type downstreamConfig struct {
+	Config
+	Topic    string        `json:"topic" validate:"required"`
+	Retries  int           `json:"retries" validate:"min=1,max=10"`
+	Timeout  time.Duration `json:"timeout" validate:"max=1m"`
+	Mode     string        `json:"mode" validate:"oneof=fast safe"`
+	Hosts    []string      `json:"hosts" validate:"min=2"`
+	Password string        `json:"password" config:"secret" validate:"min=8"`
+	Kafka    struct {
+		Url string `json:"url" validate:"required,url"`
+	} `json:"kafka"`
+}
+
+func TestValidate(t *testing.T) {
+	config := downstreamConfig{
+		Retries:  11,
+		Timeout:  time.Hour,
+		Mode:     "slow",
+		Hosts:    []string{"a"},
+		Password: "short-pw",
+	}
+	config.CamundaUrl = "not a url"
+	config.SmartServiceRepositoryUrl = "http://repository:8080"
+	config.CamundaWorkerTopic = "topic"
+	config.CamundaLockDurationInMs = 60000
+	config.CamundaFetchMaxTasks = 1
+	config.CacheBackend = "memcached"
+
+	err := Validate(config)
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) {
+		t.Fatal(err)
+	}
+	actual := map[string]string{}
+	for _, field := range validationErr.Fields {
+		actual[field.Field+" "+field.Env] = field.Message
+	}
+	expected := map[string]string{
+		"camunda_url CAMUNDA_URL":     "must be an absolute url",
+		"cache_backend CACHE_BACKEND": "must be one of memory, redis",
+		"topic TOPIC":                 "is required",
+		"retries RETRIES":             "must be at most 10",
+		"timeout TIMEOUT":             "must be at most 1m",
+		"mode MODE":                   "must be one of fast, safe",
+		"hosts HOSTS":                 "must be at least 2",
+		"kafka.url KAFKA_URL":         "is required",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Error(actual)
+	}
+	if strings.Contains(err.Error(), "not a url") {
+		t.Error("values should not be part of the error", err)
+	}
+
+	config.Kafka.Url = "kafka:9092"
+	if err := Validate(config); !strings.Contains(err.Error(), "kafka.url (KAFKA_URL): must be an absolute url") {
+		t.Error(err)
+	}
+
+	type invalidTag struct {
+		Value string `validate:"between=1 2"`
+	}
+	if err := Validate(invalidTag{Value: "a"}); err == nil || !strings.Contains(err.Error(), "unknown rule") {
+		t.Error(err)
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	location := writeConfigFile(t, `{"camunda_url": "http://camunda:8080", "smart_service_repository_url": "http://repository:8080", "camunda_worker_topic": "topic"}`)
+	config, err := LoadLibConfig(location)
+	if err != nil || config.CamundaUrl != "http://camunda:8080" {
+		t.Error(err, config)
+	}
+
+	location = writeConfigFile(t, `{"camunda_url": "camunda", "log_level": "verbose"}`)
+	_, err = LoadLibConfig(location)
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 4 {
+		t.Error("expected every invalid field in one error", err)
+	}
+
+	_, err = Load[downstreamConfig](location)
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 6 {
+		t.Error("downstream configs should be validated", err)
+	}
+
+	// explicit zero values replace the defaults and must not pass
+	location = writeConfigFile(t, `{"camunda_url": "http://camunda:8080", "smart_service_repository_url": "http://repository:8080", "camunda_worker_topic": "topic", "camunda_lock_duration_in_ms": 0}`)
+	_, err = LoadLibConfig(location)
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != "camunda_lock_duration_in_ms" {
+		t.Error("expected invalid camunda_lock_duration_in_ms", err)
+	}
+}
+
+type envConfig struct {
+	Count     int               `json:"count"`
+	Enabled   bool              `json:"enabled"`
+	Ratio     float64           `json:"ratio"`
+	Names     []string          `json:"names"`
+	Ports     []int             `json:"ports"`
+	Weights   []float64         `json:"weights"`
+	Labels    map[string]string `json:"labels"`
+	Limits    map[string]int    `json:"limits"`
+	Timeout   time.Duration     `json:"timeout"`
+	Interval  time.Duration     `json:"interval"`
+	Optional  *int              `json:"optional"`
+	Address   net.IP            `json:"address"`
+	Gateway   *net.IP           `json:"gateway"`
+	Secret    int               `json:"secret" config:"secret"`
+	Untouched string            `json:"untouched"`
+}
+
+func TestEnvironmentVars(t *testing.T) {
+	t.Setenv("COUNT", "42")
+	t.Setenv("ENABLED", "true")
+	t.Setenv("RATIO", "0.5")
+	t.Setenv("NAMES", "a, b")
+	t.Setenv("PORTS", "80, 443")
+	t.Setenv("WEIGHTS", "0.1,0.9")
+	t.Setenv("LABELS", "a:1, url:http://localhost:8080")
+	t.Setenv("LIMITS", "a:1, b:2")
+	t.Setenv("TIMEOUT", "1m30s")
+	t.Setenv("INTERVAL", "1000")
+	t.Setenv("OPTIONAL", "7")
+	t.Setenv("ADDRESS", "10.0.0.1")
+	t.Setenv("GATEWAY", "10.0.0.254")
+
+	config := envConfig{Untouched: "from file"}
+	err := handleEnvironmentVars(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	optional := 7
+	gateway := net.ParseIP("10.0.0.254")
+	expected := envConfig{
+		Count:     42,
+		Enabled:   true,
+		Ratio:     0.5,
+		Names:     []string{"a", "b"},
+		Ports:     []int{80, 443},
+		Weights:   []float64{0.1, 0.9},
+		Labels:    map[string]string{"a": "1", "url": "http://localhost:8080"},
+		Limits:    map[string]int{"a": 1, "b": 2},
+		Timeout:   90 * time.Second,
+		Interval:  1000,
+		Optional:  &optional,
+		Address:   net.ParseIP("10.0.0.1"),
+		Gateway:   &gateway,
+		Untouched: "from file",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("%#v", config)
+	}
+}
+
+func TestEnvironmentVarErrors(t *testing.T) {
+	t.Setenv("COUNT", "many")
+	t.Setenv("ENABLED", "yes please")
+	t.Setenv("PORTS", "80, https")
+	t.Setenv("LABELS", "a:1, b")
+	t.Setenv("LIMITS", "a:x")
+	t.Setenv("TIMEOUT", "soon")
+	t.Setenv("ADDRESS", "localhost")
+	t.Setenv("SECRET", "hunter2")
+
+	err := handleEnvironmentVars(&envConfig{})
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) {
+		t.Fatal(err)
+	}
+	invalid := []string{}
+	for _, field := range validationErr.Fields {
+		invalid = append(invalid, field.Env)
+	}
+	if !reflect.DeepEqual(invalid, []string{"COUNT", "ENABLED", "PORTS", "LABELS", "LIMITS", "TIMEOUT", "ADDRESS", "SECRET"}) {
+		t.Error(invalid, err)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Error("secret values should not be part of the error", err)
+	}
+
+	location := writeConfigFile(t, `{}`)
+	_, err = Load[envConfig](location)
+	if !errors.As(err, &validationErr) {
+		t.Error("Load should return parse errors", err)
+	}
+}
+
+type kafkaConfig struct {
+	Url    string   `json:"url"`
+	Topics []string `json:"topics"`
+	Auth   struct {
+		User     string `json:"user"`
+		Password string `json:"password" config:"secret"`
+	} `json:"auth"`
+}
+
+type nestedConfig struct {
+	Config
+	Kafka    kafkaConfig `json:"kafka"`
+	Database *struct {
+		Host string `json:"host"`
+		Port int    `json:"port" validate:"max=65535"`
+	} `json:"database"`
+	Unused *struct {
+		Host string `json:"host"`
+	} `json:"unused"`
+	Credentials struct {
+		Token string `json:"token"`
+		Count int    `json:"count"`
+	} `json:"credentials" config:"secret"`
+	Started time.Time `json:"started"`
+}
+
+func TestNestedEnvironmentVars(t *testing.T) {
+	t.Setenv("CAMUNDA_URL", "http://camunda:8080")
+	t.Setenv("KAFKA_URL", "kafka:9092")
+	t.Setenv("KAFKA_TOPICS", "a,b")
+	t.Setenv("KAFKA_AUTH_USER", "user")
+	t.Setenv("KAFKA_AUTH_PASSWORD", "password")
+	t.Setenv("DATABASE_PORT", "5432")
+	t.Setenv("CREDENTIALS_TOKEN", "token")
+	t.Setenv("STARTED", "2026-01-02T03:04:05Z")
+
+	config := nestedConfig{}
+	config.Kafka.Url = "from file"
+	err := handleEnvironmentVars(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CamundaUrl != "http://camunda:8080" {
+		t.Error("embedded fields should not have a prefix", config.CamundaUrl)
+	}
+	if config.Kafka.Url != "kafka:9092" || !reflect.DeepEqual(config.Kafka.Topics, []string{"a", "b"}) || config.Kafka.Auth.User != "user" || config.Kafka.Auth.Password != "password" {
+		t.Errorf("%#v", config.Kafka)
+	}
+	if config.Database == nil || config.Database.Port != 5432 {
+		t.Error("pointers should be allocated if a nested field is set", config.Database)
+	}
+	if config.Unused != nil {
+		t.Error("pointers without nested values should stay nil", config.Unused)
+	}
+	if config.Credentials.Token != "token" {
+		t.Error(config.Credentials)
+	}
+	if !config.Started.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Error(config.Started)
+	}
+
+	t.Setenv("CREDENTIALS_COUNT", "secret-count")
+	t.Setenv("KAFKA_AUTH_PASSWORD", "")
+	t.Setenv("DATABASE_PORT", "70000")
+	location := writeConfigFile(t, `{"smart_service_repository_url": "http://repository:8080", "camunda_worker_topic": "topic"}`)
+	_, err = Load[nestedConfig](location)
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Env != "CREDENTIALS_COUNT" {
+		t.Fatal(err)
+	}
+	if strings.Contains(err.Error(), "secret-count") {
+		t.Error("secret tags should apply to nested fields", err)
+	}
+
+	t.Setenv("CREDENTIALS_COUNT", "")
+	_, err = Load[nestedConfig](location)
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != "database.port" {
+		t.Error("nested pointers should be validated", err)
+	}
+}
+
+type recursiveConfig struct {
+	Foo   string           `json:"foo" default:"foo"`
+	Child *recursiveConfig `json:"child"`
+}
+
+func TestRecursiveEnvironmentVars(t *testing.T) {
+	t.Setenv("FOO", "x")
+	config := recursiveConfig{}
+	err := handleEnvironmentVars(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Foo != "x" || config.Child != nil {
+		t.Errorf("%#v", config)
+	}
+
+	t.Setenv("CHILD_CHILD_FOO", "y")
+	config = recursiveConfig{}
+	err = handleEnvironmentVars(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Child == nil || config.Child.Foo != "" || config.Child.Child == nil || config.Child.Child.Foo != "y" || config.Child.Child.Child != nil {
+		t.Errorf("%#v", config)
+	}
+}
+
+type defaultsConfig struct {
+	Config
+	Topic   string         `json:"topic" default:"events"`
+	Retries int            `json:"retries" default:"3"`
+	Timeout time.Duration  `json:"timeout" default:"30s"`
+	Hosts   []string       `json:"hosts" default:"a,b"`
+	Limits  map[string]int `json:"limits" default:"a:1"`
+	Kafka   *struct {
+		Url string `json:"url" default:"http://kafka:9092"`
+	} `json:"kafka"`
+}
+
+func TestDefaults(t *testing.T) {
+	t.Setenv("CAMUNDA_URL", "http://camunda:8080")
+	t.Setenv("SMART_SERVICE_REPOSITORY_URL", "http://repository:8080")
+	t.Setenv("CAMUNDA_WORKER_TOPIC", "topic")
+	t.Setenv("RETRIES", "5")
+
+	t.Run("without config file", func(t *testing.T) {
+		for _, location := range []string{"", filepath.Join(t.TempDir(), "missing.json")} {
+			config, err := Load[defaultsConfig](location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.CamundaUrl != "http://camunda:8080" || config.CamundaLockDurationInMs != 60000 || config.AuthRealm != "master" || config.LogLevel != "info" {
+				t.Errorf("%#v", config.Config)
+			}
+			if config.Topic != "events" || config.Retries != 5 || config.Timeout != 30*time.Second || !reflect.DeepEqual(config.Hosts, []string{"a", "b"}) {
+				t.Errorf("%#v", config)
+			}
+			if config.Kafka == nil || config.Kafka.Url != "http://kafka:9092" {
+				t.Error(config.Kafka)
+			}
+		}
+	})
+
+	t.Run("file overwrites defaults", func(t *testing.T) {
+		location := writeConfigFile(t, `{"topic": "from-file", "retries": 1, "hosts": ["c"], "log_level": "debug", "kafka": {"url": "http://other:9092"}}`)
+		config, err := Load[defaultsConfig](location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Topic != "from-file" || config.Retries != 5 || !reflect.DeepEqual(config.Hosts, []string{"c"}) || config.LogLevel != "debug" || config.Kafka.Url != "http://other:9092" {
+			t.Errorf("%#v", config)
+		}
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, err := Load[defaultsConfig](writeConfigFile(t, `{"topic": `))
+		if err == nil {
+			t.Error("existing files should still be parsed strictly")
+		}
+	})
+
+	t.Run("recursive type", func(t *testing.T) {
+		config, err := Load[recursiveConfig]("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Foo != "foo" || config.Child != nil {
+			t.Errorf("%#v", config)
+		}
+	})
+
+	t.Run("invalid default", func(t *testing.T) {
+		type invalidDefault struct {
+			Count int `json:"count" default:"many"`
+		}
+		_, err := Load[invalidDefault]("")
+		validationErr := &ValidationError{}
+		if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), "count (COUNT): invalid default") {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2022 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package configuration
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// preparations for docker
+// handleEnvironmentVars overwrites fields with the values of the matching environment variables (e.g KafkaUrl --> KAFKA_URL).
+// fields of nested structs use the name of the struct field as prefix (e.g. Kafka.Url --> KAFKA_URL);
+// fields of embedded structs have no prefix. A config:"secret" tag on a struct field applies to all its nested fields.
+// values that can not be parsed are reported as *ValidationError, listing every invalid variable.
+func handleEnvironmentVars[T any](config *T) error {
+	configValue := reflect.Indirect(reflect.ValueOf(config))
+	if configValue.Kind() != reflect.Struct {
+		return nil
+	}
+	result := &ValidationError{}
+	overlay(configValue, "", "", false, environmentSource, nil, result)
+	if len(result.Fields) > 0 {
+		return result
+	}
+	return nil
+}
+
+// applyDefaults sets fields with a `default:"..."` tag to the parsed tag value (like environment variables);
+// nil pointers to structs are allocated if a nested field has a default, except for recursive types
+func applyDefaults[T any](config *T) error {
+	configValue := reflect.Indirect(reflect.ValueOf(config))
+	if configValue.Kind() != reflect.Struct {
+		return nil
+	}
+	result := &ValidationError{}
+	overlay(configValue, "", "", false, defaultSource, nil, result)
+	if len(result.Fields) > 0 {
+		return result
+	}
+	return nil
+}
+
+// valueSource returns the raw value of a field for overlay
+type valueSource struct {
+	name   string //used in error messages
+	lookup func(field reflect.StructField, envName string, secret bool) (raw string, ok bool)
+	// hasPrefix returns true if the source may have values for fields below envPrefix;
+	// used to stop at nil pointers of recursive types
+	hasPrefix func(envPrefix string) bool
+}
+
+var environmentSource = valueSource{
+	name: "value",
+	lookup: func(field reflect.StructField, envName string, secret bool) (string, bool) {
+		envValue := os.Getenv(envName)
+		if envValue == "" {
+			return "", false
+		}
+		if !secret {
+			fmt.Println("use environment variable: ", envName, " = ", envValue)
+		}
+		return envValue, true
+	},
+	hasPrefix: func(envPrefix string) bool {
+		for _, env := range os.Environ() {
+			name, value, _ := strings.Cut(env, "=")
+			if value != "" && strings.HasPrefix(name, envPrefix) {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+var defaultSource = valueSource{
+	name: "default",
+	lookup: func(field reflect.StructField, envName string, secret bool) (string, bool) {
+		return field.Tag.Lookup("default")
+	},
+	hasPrefix: func(envPrefix string) bool {
+		return false
+	},
+}
+
+// overlay sets the fields of the struct value from source; returns true if a field was set.
+// parents are the struct types on the current path, to detect recursive types.
+func overlay(value reflect.Value, path string, envPrefix string, secret bool, source valueSource, parents []reflect.Type, result *ValidationError) (changed bool) {
+	valueType := value.Type()
+	parents = append(parents[:len(parents):len(parents)], valueType)
+	for index := 0; index < valueType.NumField(); index++ {
+		field := valueType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := value.Field(index)
+		fieldSecret := secret || strings.Contains(field.Tag.Get("config"), "secret")
+		fieldPath := jsonFieldName(field)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		envName := envPrefix + fieldNameToEnvName(field.Name)
+		if isNested(field.Type) {
+			if field.Anonymous {
+				fieldPath, envName = path, strings.TrimSuffix(envPrefix, "_")
+			}
+			nestedPrefix := envName + "_"
+			if envName == "" {
+				nestedPrefix = ""
+			}
+			if overlayNested(fieldValue, fieldPath, nestedPrefix, fieldSecret, source, parents, result) {
+				changed = true
+			}
+			continue
+		}
+		raw, ok := source.lookup(field, envName, fieldSecret)
+		if !ok {
+			continue
+		}
+		err := setFromString(fieldValue, raw)
+		if err != nil {
+			message := fmt.Sprintf("invalid %v: %v", source.name, err)
+			if fieldSecret {
+				// parse errors may contain the value
+				message = fmt.Sprintf("invalid %v for %v", source.name, field.Type)
+			}
+			result.Fields = append(result.Fields, FieldError{Field: fieldPath, Env: envName, Message: message})
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+// overlayNested handles struct and pointer to struct fields; nil pointers are only allocated if a nested field is set.
+// nil pointers to a type already on the path (e.g. type Node struct{ Child *Node }) are only followed
+// while the source has values with the nested prefix, so recursive types terminate.
+func overlayNested(value reflect.Value, path string, envPrefix string, secret bool, source valueSource, parents []reflect.Type, result *ValidationError) bool {
+	if value.Kind() != reflect.Pointer {
+		return overlay(value, path, envPrefix, secret, source, parents, result)
+	}
+	if !value.IsNil() {
+		return overlay(value.Elem(), path, envPrefix, secret, source, parents, result)
+	}
+	if slices.Contains(parents, value.Type().Elem()) && (envPrefix == "" || !source.hasPrefix(envPrefix)) {
+		return false
+	}
+	element := reflect.New(value.Type().Elem())
+	if !overlay(element.Elem(), path, envPrefix, secret, source, parents, result) {
+		return false
+	}
+	value.Set(element)
+	return true
+}
+
+// isNested returns true for struct and pointer to struct fields, that are handled field by field;
+// structs implementing encoding.TextUnmarshaler (e.g. time.Time) are handled as single values
+func isNested(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(textUnmarshalerType)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setFromString parses raw into value:
+//   - encoding.TextUnmarshaler implementations (e.g. net.IP) use UnmarshalText
+//   - time.Duration values are parsed with time.ParseDuration (e.g. "1m30s"); plain numbers are nanoseconds
+//   - pointers are allocated
+//   - slices are comma separated lists (e.g. "1, 2, 3")
+//   - maps are comma separated lists of key:value pairs (e.g. "a:1, b:2")
+func setFromString(value reflect.Value, raw string) error {
+	if value.Kind() != reflect.Pointer && value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			nanoseconds, intErr := strconv.ParseInt(raw, 10, 64)
+			if intErr != nil {
+				return err
+			}
+			duration = time.Duration(nanoseconds)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Pointer:
+		element := reflect.New(value.Type().Elem())
+		err := setFromString(element.Elem(), raw)
+		if err != nil {
+			return err
+		}
+		value.Set(element)
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		elements := strings.Split(raw, ",")
+		result := reflect.MakeSlice(value.Type(), len(elements), len(elements))
+		for i, element := range elements {
+			err := setFromString(result.Index(i), strings.TrimSpace(element))
+			if err != nil {
+				return fmt.Errorf("element %v: %w", i, err)
+			}
+		}
+		value.Set(result)
+	case reflect.Map:
+		result := reflect.MakeMap(value.Type())
+		for i, element := range strings.Split(raw, ",") {
+			rawKey, rawValue, ok := strings.Cut(element, ":")
+			if !ok {
+				return fmt.Errorf("element %v: expected key:value", i)
+			}
+			key := reflect.New(value.Type().Key()).Elem()
+			err := setFromString(key, strings.TrimSpace(rawKey))
+			if err != nil {
+				return fmt.Errorf("key of element %v: %w", i, err)
+			}
+			mapValue := reflect.New(value.Type().Elem()).Elem()
+			err = setFromString(mapValue, strings.TrimSpace(rawValue))
+			if err != nil {
+				return fmt.Errorf("value of element %v: %w", i, err)
+			}
+			result.SetMapIndex(key, mapValue)
+		}
+		value.Set(result)
+	default:
+		return errors.New("unsupported field type " + value.Type().String())
+	}
+	return nil
+}
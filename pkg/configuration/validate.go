@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes an invalid config field; values are not included, because they may be secret
+type FieldError struct {
+	Field   string //json name, e.g. "camunda_url"; nested fields are joined with "."
+	Env     string //name of the environment variable, e.g. "CAMUNDA_URL"
+	Message string
+}
+
+func (this FieldError) Error() string {
+	return fmt.Sprintf("%v (%v): %v", this.Field, this.Env, this.Message)
+}
+
+// ValidationError lists every invalid field of a config
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (this *ValidationError) Error() string {
+	messages := []string{}
+	for _, field := range this.Fields {
+		messages = append(messages, field.Error())
+	}
+	return "invalid config: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the `validate` struct tags of config (a struct or pointer to a struct), e.g.
+//
+//	CamundaUrl string `json:"camunda_url" validate:"required,url"`
+//
+// rules are separated by ",":
+//   - required: the value may not be the zero value
+//   - min=<n>, max=<n>: bounds of numbers, time.Duration (e.g. "min=1s") or the length of strings, slices and maps
+//   - url: an absolute url with scheme and host
+//   - oneof=<a> <b> ...: one of the space separated values
+//
+// except for required, rules are not checked for zero values, so that optional fields may stay empty.
+// nested and embedded structs (and non-nil pointers to structs) are checked recursively. The returned error is a *ValidationError listing every invalid field;
+// an invalid tag is reported as invalid field, too.
+func Validate(config interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(config))
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("unable to validate %T: expected struct", config)
+	}
+	result := &ValidationError{}
+	validateStruct(value, "", "", result)
+	if len(result.Fields) > 0 {
+		return result
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, path string, envPrefix string, result *ValidationError) {
+	valueType := value.Type()
+	for index := 0; index < valueType.NumField(); index++ {
+		field := valueType.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := value.Field(index)
+		name := jsonFieldName(field)
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		env := envPrefix + fieldNameToEnvName(field.Name)
+		nested := reflect.Indirect(fieldValue)
+		if field.Anonymous && isNested(field.Type) {
+			if nested.IsValid() {
+				validateStruct(nested, path, envPrefix, result)
+			}
+			continue
+		}
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "" {
+				continue
+			}
+			err := validateRule(fieldValue, rule)
+			if err != nil {
+				result.Fields = append(result.Fields, FieldError{Field: fieldPath, Env: env, Message: err.Error()})
+			}
+		}
+		if isNested(field.Type) && nested.IsValid() {
+			validateStruct(nested, fieldPath, env+"_", result)
+		}
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func validateRule(value reflect.Value, rule string) error {
+	name, param, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if value.IsZero() {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if value.IsZero() {
+		return nil
+	}
+	switch name {
+	case "min", "max":
+		actual, limit, err := numeric(value, param)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q: %w", rule, err)
+		}
+		if name == "min" && actual < limit {
+			return fmt.Errorf("must be at least %v", param)
+		}
+		if name == "max" && actual > limit {
+			return fmt.Errorf("must be at most %v", param)
+		}
+		return nil
+	case "url":
+		if value.Kind() != reflect.String {
+			return fmt.Errorf("invalid rule %q: expected string field", rule)
+		}
+		parsed, err := url.Parse(value.String())
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return errors.New("must be an absolute url")
+		}
+		return nil
+	case "oneof":
+		allowed := strings.Fields(param)
+		if !slices.Contains(allowed, fmt.Sprint(value.Interface())) {
+			return fmt.Errorf("must be one of %v", strings.Join(allowed, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown rule %q", rule)
+	}
+}
+
+// numeric returns the number (or length) of value and param as float64
+func numeric(value reflect.Value, param string) (actual float64, limit float64, err error) {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(param)
+		return float64(value.Int()), float64(duration), err
+	}
+	limit, err = strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), limit, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), limit, nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), limit, nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), limit, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported field type %v", value.Type())
+	}
+}
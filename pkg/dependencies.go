@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"log/slog"
+
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/middleware"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/smartservicerepository"
+)
+
+// Dependencies bundles everything the lib creates for a worker
+type Dependencies struct {
+	Auth             *auth.Auth
+	SmartServiceRepo *smartservicerepository.SmartServiceRepository
+	IotClient        client.Interface
+	Config           configuration.Config
+	TaskLogger       TaskLoggerFactory
+	Engine           camunda.Engine
+	ScriptRunner     *middleware.ScriptRunner
+}
+
+// TaskLoggerFactory returns a logger with the task id, process instance id and worker topic as attributes
+type TaskLoggerFactory = func(task model.CamundaExternalTask) *slog.Logger
+
+type DependencyHandlerFactory = func(deps Dependencies) (camunda.Handler, error)
+
+func NewTaskLoggerFactory(config configuration.Config) TaskLoggerFactory {
+	logger := config.GetLogger()
+	return func(task model.CamundaExternalTask) *slog.Logger {
+		return logger.With("taskId", task.Id, "processInstanceId", task.ProcessInstanceId, "topic", config.CamundaWorkerTopic)
+	}
+}
@@ -26,26 +26,27 @@ import (
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/middleware/references"
-	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/middleware/scriptenv"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
 )
 
 func New(config configuration.Config, handler camunda.Handler, repo VariablesRepo, auth Auth, iotClient client.Interface) *Middleware {
+	return NewWithScriptRunner(config, handler, repo, NewScriptRunner(auth, iotClient))
+}
+
+func NewWithScriptRunner(config configuration.Config, handler camunda.Handler, repo VariablesRepo, scriptRunner *ScriptRunner) *Middleware {
 	return &Middleware{
-		handler:   handler,
-		repo:      repo,
-		auth:      auth,
-		iotClient: iotClient,
-		config:    config,
+		handler:      handler,
+		repo:         repo,
+		scriptRunner: scriptRunner,
+		config:       config,
 	}
 }
 
 type Middleware struct {
-	handler   camunda.Handler
-	repo      VariablesRepo
-	auth      Auth
-	iotClient client.Interface
-	config    configuration.Config
+	handler      camunda.Handler
+	repo         VariablesRepo
+	scriptRunner *ScriptRunner
+	config       configuration.Config
 }
 
 type Auth interface {
@@ -154,10 +155,5 @@ func (this *Middleware) RunScripts(userId string, prefix string, inputs map[stri
 		scripts = append(scripts, script.Value)
 	}
 	script := strings.Join(scripts, "")
-	scriptEnv := scriptenv.NewScriptEnv(this.auth, this.iotClient, userId, variables, inputs, existingOutputs)
-	err = runScript(script, scriptEnv)
-	if err != nil {
-		return variableChanges, outputs, err
-	}
-	return scriptEnv.GetUpdatedVariables(), scriptEnv.GetOutputs(), nil
+	return this.scriptRunner.Run(userId, script, inputs, existingOutputs, variables)
 }
@@ -17,11 +17,17 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
-	"github.com/SENERGY-Platform/models/go/models"
-	"github.com/dop251/goja"
+	"net/http"
 	"reflect"
 	"testing"
+
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	devicemodel "github.com/SENERGY-Platform/device-repository/lib/model"
+	"github.com/SENERGY-Platform/models/go/models"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authmock"
+	"github.com/dop251/goja"
 )
 
 type ScriptContextMock struct {
@@ -176,3 +182,79 @@ io.assertDeviceName(goDevice.name, scriptDevice);
 		}
 	})
 }
+
+func TestScriptRunner(t *testing.T) {
+	runner := NewScriptRunner(AuthMock, nil)
+	variableChanges, outputs, err := runner.Run("user", `
+variables.write("v2", inputs.get("in") + variables.read("v1"));
+outputs.set("out", inputs.get("in"));
+`, map[string]interface{}{"in": "foo"}, map[string]interface{}{"existing": true}, map[string]interface{}{"v1": "bar"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(variableChanges, map[string]interface{}{"v2": "foobar"}) {
+		t.Error(variableChanges)
+	}
+	if !reflect.DeepEqual(outputs, map[string]interface{}{"existing": true, "out": "foo"}) {
+		t.Error(outputs)
+	}
+}
+
+// UnauthorizedIotClientMock rejects every token in Rejected with 401
+type UnauthorizedIotClientMock struct {
+	client.Interface
+	Rejected map[string]bool
+	Tokens   []string
+}
+
+func (this *UnauthorizedIotClientMock) ReadDevice(id string, token string, action devicemodel.AuthAction) (result models.Device, err error, errCode int) {
+	this.Tokens = append(this.Tokens, token)
+	if this.Rejected[token] {
+		return result, errors.New("unauthorized"), http.StatusUnauthorized
+	}
+	return models.Device{Id: id, Name: "device-name", DeviceTypeId: "device-type"}, nil, http.StatusOK
+}
+
+func (this *UnauthorizedIotClientMock) GetDeviceTypeSelectablesV2(query []devicemodel.FilterCriteria, pathPrefix string, includeModified bool, servicesMustMatchAllCriteria bool) (result []devicemodel.DeviceTypeSelectable, err error, code int) {
+	return []devicemodel.DeviceTypeSelectable{{DeviceTypeId: "device-type", Services: []models.Service{{Id: "service-id"}}}}, nil, http.StatusOK
+}
+
+func TestScriptRunnerRetriesUnauthorized(t *testing.T) {
+	script := `outputs.set("name", deviceRepo.readDevice("device-id").name);`
+
+	t.Run("retry with fresh token", func(t *testing.T) {
+		a := &authmock.RotatingAuth{}
+		iot := &UnauthorizedIotClientMock{Rejected: map[string]bool{"token-0": true}}
+		_, outputs, err := NewScriptRunner(a, iot).Run("user", script, map[string]interface{}{}, map[string]interface{}{}, map[string]interface{}{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if outputs["name"] != "device-name" || len(a.Invalidations()) != 1 || !reflect.DeepEqual(iot.Tokens, []string{"token-0", "token-1"}) {
+			t.Error(outputs, a.Invalidations(), iot.Tokens)
+		}
+	})
+
+	t.Run("retry exactly once", func(t *testing.T) {
+		a := &authmock.RotatingAuth{}
+		iot := &UnauthorizedIotClientMock{Rejected: map[string]bool{"token-0": true, "token-1": true, "token-2": true}}
+		_, _, err := NewScriptRunner(a, iot).Run("user", script, map[string]interface{}{}, map[string]interface{}{}, map[string]interface{}{})
+		if err == nil || len(a.Invalidations()) != 1 || len(iot.Tokens) != 2 {
+			t.Error(err, a.Invalidations(), iot.Tokens)
+		}
+	})
+
+	t.Run("util device lookup", func(t *testing.T) {
+		a := &authmock.RotatingAuth{}
+		iot := &UnauthorizedIotClientMock{Rejected: map[string]bool{"token-0": true}}
+		_, outputs, err := NewScriptRunner(a, iot).Run("user", `outputs.set("count", util.getDevicesWithServiceFromEntityString('{"device_selection":{"device_id":"device-id"}}', []).length);`, map[string]interface{}{}, map[string]interface{}{}, map[string]interface{}{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if fmt.Sprint(outputs["count"]) != "1" || len(a.Invalidations()) != 1 || !reflect.DeepEqual(iot.Tokens, []string{"token-0", "token-1"}) {
+			t.Error(outputs, a.Invalidations(), iot.Tokens)
+		}
+	})
+}
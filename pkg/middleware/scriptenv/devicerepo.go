@@ -36,7 +36,9 @@ func (this *ScriptEnvDeviceRepo) ReadDevice(id string) models.Device {
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ReadDevice(id, this.env.getToken(), model.READ)
+	result, err, _ := withUserToken(this.env, func(token string) (models.Device, error, int) {
+		return this.env.iotClient.ReadDevice(id, token, model.READ)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ReadDevice(%#v): %v", id, err))
 	}
@@ -50,7 +52,9 @@ func (this *ScriptEnvDeviceRepo) ReadDeviceByLocalId(localId string) models.Devi
 		}
 	}()
 	//device-repository replaces ownerId="" with the requesting user id
-	result, err, _ := this.env.iotClient.ReadDeviceByLocalId("", localId, this.env.getToken(), model.READ)
+	result, err, _ := withUserToken(this.env, func(token string) (models.Device, error, int) {
+		return this.env.iotClient.ReadDeviceByLocalId("", localId, token, model.READ)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ReadDeviceByLocalId(%#v): %v", localId, err))
 	}
@@ -63,7 +67,9 @@ func (this *ScriptEnvDeviceRepo) ReadHub(id string) models.Hub {
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ReadHub(id, this.env.getToken(), model.READ)
+	result, err, _ := withUserToken(this.env, func(token string) (models.Hub, error, int) {
+		return this.env.iotClient.ReadHub(id, token, model.READ)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ReadHub(%#v): %v", id, err))
 	}
@@ -76,7 +82,9 @@ func (this *ScriptEnvDeviceRepo) ListHubDeviceIds(id string, asLocalId bool) []s
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ListHubDeviceIds(id, this.env.getToken(), model.READ, asLocalId)
+	result, err, _ := withUserToken(this.env, func(token string) ([]string, error, int) {
+		return this.env.iotClient.ListHubDeviceIds(id, token, model.READ, asLocalId)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ListHubDeviceIds(%#v, %#v): %v", id, asLocalId, err))
 	}
@@ -89,7 +97,9 @@ func (this *ScriptEnvDeviceRepo) ReadDeviceType(id string) models.DeviceType {
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ReadDeviceType(id, this.env.getToken())
+	result, err, _ := withUserToken(this.env, func(token string) (models.DeviceType, error, int) {
+		return this.env.iotClient.ReadDeviceType(id, token)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ReadDeviceType(%#v): %v", id, err))
 	}
@@ -102,7 +112,9 @@ func (this *ScriptEnvDeviceRepo) ListDeviceTypes(limit int64, offset int64, sort
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ListDeviceTypesV2(this.env.getToken(), limit, offset, sort, filter, includeModified, includeUnmodified)
+	result, err, _ := withUserToken(this.env, func(token string) ([]models.DeviceType, error, int) {
+		return this.env.iotClient.ListDeviceTypesV2(token, limit, offset, sort, filter, includeModified, includeUnmodified)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ListDeviceTypes(): %v", err))
 	}
@@ -128,7 +140,9 @@ func (this *ScriptEnvDeviceRepo) ReadDeviceGroup(id string) models.DeviceGroup {
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.ReadDeviceGroup(id, this.env.getToken(), false)
+	result, err, _ := withUserToken(this.env, func(token string) (models.DeviceGroup, error, int) {
+		return this.env.iotClient.ReadDeviceGroup(id, token, false)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in ReadDeviceGroup(%#v): %v", id, err))
 	}
@@ -401,7 +415,9 @@ func (this *ScriptEnvDeviceRepo) GetLocation(id string) models.Location {
 			panic(this.env.GetVm().ToValue(caught))
 		}
 	}()
-	result, err, _ := this.env.iotClient.GetLocation(id, this.env.getToken())
+	result, err, _ := withUserToken(this.env, func(token string) (models.Location, error, int) {
+		return this.env.iotClient.GetLocation(id, token)
+	})
 	if err != nil {
 		panic(fmt.Errorf("error in GetLocation(%#v): %v", id, err))
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
 	"github.com/dop251/goja"
+	"net/http"
 	"strings"
 	"sync"
 )
@@ -58,6 +59,12 @@ type Auth interface {
 	ExchangeUserToken(userid string) (token auth.Token, err error)
 }
 
+// TokenInvalidator is optionally implemented by Auth (e.g. *auth.Auth);
+// if it is, device-repository requests rejected with 401 are retried once with a fresh token
+type TokenInvalidator interface {
+	InvalidateUserToken(userid string)
+}
+
 type VariablesRepo interface {
 	GetVariables(processId string) (result map[string]interface{}, err error)
 	SetVariables(processId string, changes map[string]interface{}) error
@@ -116,3 +123,30 @@ func (this *ScriptEnv) getToken() string {
 	this.userToken = token.Jwt()
 	return this.userToken
 }
+
+// invalidateToken drops token if it is still the current user token;
+// returns false if Auth does not implement TokenInvalidator, because a fresh token can not be requested in that case
+func (this *ScriptEnv) invalidateToken(token string) bool {
+	invalidator, ok := this.auth.(TokenInvalidator)
+	if !ok {
+		return false
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	if this.userToken == token {
+		this.userToken = ""
+		invalidator.InvalidateUserToken(this.userId)
+	}
+	return true
+}
+
+// withUserToken calls request with the user token; if the response code is 401,
+// the token is invalidated and request is retried exactly once with a fresh token
+func withUserToken[T any](env *ScriptEnv, request func(token string) (T, error, int)) (T, error, int) {
+	token := env.getToken()
+	result, err, code := request(token)
+	if code != http.StatusUnauthorized || !env.invalidateToken(token) {
+		return result, err, code
+	}
+	return request(env.getToken())
+}
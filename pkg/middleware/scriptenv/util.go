@@ -69,8 +69,9 @@ func (this *ScriptEnvUtil) GetDevicesWithServiceFromEntityString(entityStr strin
 }
 
 func (this *ScriptEnvUtil) getDevicesWithServiceFromIotOption(entity model.IotOption, criteria []devicemodel.FilterCriteria) (result []model.IotOption) {
-	var err error
-	result, err = util.GetDevicesWithService(this.env.iotClient, this.env.getToken(), entity, criteria)
+	result, err, _ := withUserToken(this.env, func(token string) ([]model.IotOption, error, int) {
+		return util.GetDevicesWithServiceWithCode(this.env.iotClient, token, entity, criteria)
+	})
 	if errors.Is(err, util.ErrNoDeviceOrGroupSelection) {
 		return []model.IotOption{}
 	}
@@ -17,8 +17,11 @@
 package middleware
 
 import (
-	"github.com/dop251/goja"
 	"time"
+
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/middleware/scriptenv"
+	"github.com/dop251/goja"
 )
 
 type ScriptContext interface {
@@ -43,3 +46,24 @@ func runScript(script string, ctx ScriptContext) (err error) {
 	_, err = vm.RunString(script)
 	return err
 }
+
+// ScriptRunner executes user scripts in the same environment as the pre- and post-scripts
+type ScriptRunner struct {
+	auth      Auth
+	iotClient client.Interface
+}
+
+func NewScriptRunner(auth Auth, iotClient client.Interface) *ScriptRunner {
+	return &ScriptRunner{auth: auth, iotClient: iotClient}
+}
+
+// Run executes the script for userId and returns the variable changes and the outputs
+// (existingOutputs extended by the outputs set in the script)
+func (this *ScriptRunner) Run(userId string, script string, inputs map[string]interface{}, existingOutputs map[string]interface{}, variables map[string]interface{}) (variableChanges map[string]interface{}, outputs map[string]interface{}, err error) {
+	scriptEnv := scriptenv.NewScriptEnv(this.auth, this.iotClient, userId, variables, inputs, existingOutputs)
+	err = runScript(script, scriptEnv)
+	if err != nil {
+		return variableChanges, outputs, err
+	}
+	return scriptEnv.GetUpdatedVariables(), scriptEnv.GetOutputs(), nil
+}
@@ -48,3 +48,30 @@ type CamundaCompleteRequest struct {
 	WorkerId  string                     `json:"workerId,omitempty"`
 	Variables map[string]CamundaVariable `json:"localVariables,omitempty"`
 }
+
+type CamundaFailureRequest struct {
+	WorkerId     string `json:"workerId,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	ErrorDetails string `json:"errorDetails,omitempty"`
+	Retries      int64  `json:"retries"`
+	RetryTimeout int64  `json:"retryTimeout"`
+}
+
+type CamundaBpmnErrorRequest struct {
+	WorkerId     string                     `json:"workerId,omitempty"`
+	ErrorCode    string                     `json:"errorCode,omitempty"`
+	ErrorMessage string                     `json:"errorMessage,omitempty"`
+	Variables    map[string]CamundaVariable `json:"variables,omitempty"`
+}
+
+type CamundaExtendLockRequest struct {
+	WorkerId    string `json:"workerId,omitempty"`
+	NewDuration int64  `json:"newDuration"`
+}
+
+type CamundaMessageRequest struct {
+	MessageName           string                     `json:"messageName"`
+	All                   bool                       `json:"all"`
+	ResultEnabled         bool                       `json:"resultEnabled"`
+	ProcessVariablesLocal map[string]CamundaVariable `json:"processVariablesLocal"`
+}
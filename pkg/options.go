@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/smartservicerepository"
+)
+
+// Option changes the dependencies used by Start; every dependency that is not set by an Option is created like before
+type Option func(*options)
+
+// Middleware wraps the camunda.Handler; it is applied around the script middleware,
+// the first Middleware passed to WithMiddlewares is the outermost
+type Middleware = func(handler camunda.Handler) camunda.Handler
+
+type options struct {
+	iotClient   client.Interface
+	auth        *auth.Auth
+	repo        *smartservicerepository.SmartServiceRepository
+	httpClient  *http.Client
+	logger      *slog.Logger
+	engine      camunda.Engine
+	middlewares []Middleware
+}
+
+// WithIotClient replaces the device-repository client (default: client.NewClient(config.DeviceRepositoryUrl, nil))
+func WithIotClient(iotClient client.Interface) Option {
+	return func(o *options) {
+		o.iotClient = iotClient
+	}
+}
+
+// WithAuth replaces the auth (default: auth.New(config)); WithHTTPClient is not applied to it.
+// config.AuthBackgroundRefresh only starts the refresher of the default auth: start it with auth.Auth.StartRefresher if needed.
+func WithAuth(a *auth.Auth) Option {
+	return func(o *options) {
+		o.auth = a
+	}
+}
+
+// WithRepository replaces the smart-service-repository client (default: smartservicerepository.New(config, auth)); WithHTTPClient is not applied to it
+func WithRepository(repo *smartservicerepository.SmartServiceRepository) Option {
+	return func(o *options) {
+		o.repo = repo
+	}
+}
+
+// WithHTTPClient sets the http.Client used by the default auth (token requests and auth metadata like discovery and jwks),
+// smart-service-repository and engine clients.
+// If httpClient has no Timeout, they use a copy with camunda.DefaultHttpTimeout, because requests must not block forever.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithLogger replaces the logger returned by configuration.Config.GetLogger; it is used by every dependency created by Start
+// (auth, smart-service-repository, engine, their caches, middlewares and Dependencies.TaskLogger).
+// Dependencies passed with WithAuth, WithRepository or WithEngine keep their own logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithEngine replaces the process-engine (default: camunda.NewClient(config)); WithHTTPClient is not applied to it
+func WithEngine(engine camunda.Engine) Option {
+	return func(o *options) {
+		o.engine = engine
+	}
+}
+
+// WithMiddlewares adds handler middlewares; may be used multiple times
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
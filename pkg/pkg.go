@@ -18,7 +18,9 @@ package pkg
 
 import (
 	"context"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
@@ -30,14 +32,78 @@ import (
 
 type HandlerFactory = func(auth *auth.Auth, smartServiceRepo *smartservicerepository.SmartServiceRepository) (camunda.Handler, error)
 
-func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory HandlerFactory) error {
-	auth := auth.New(config)
-	smartServiceRepo := smartservicerepository.New(config, auth)
-	handler, err := handlerfactory(auth, smartServiceRepo)
+func Start(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory HandlerFactory, opts ...Option) error {
+	return StartWithDependencies(ctx, wg, config, func(deps Dependencies) (camunda.Handler, error) {
+		return handlerfactory(deps.Auth, deps.SmartServiceRepo)
+	}, opts...)
+}
+
+func StartWithDependencies(ctx context.Context, wg *sync.WaitGroup, config configuration.Config, handlerfactory DependencyHandlerFactory, opts ...Option) error {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger != nil {
+		//set before any dependency is created: every dependency gets a copy of config
+		config.SetLogger(o.logger)
+	}
+	if o.httpClient != nil {
+		// requests of the worker (fetch, auth metadata, tokens) must not block forever
+		o.httpClient = withDefaultTimeout(o.httpClient, camunda.DefaultHttpTimeout)
+	}
+	if o.auth == nil {
+		o.auth = auth.New(config)
+		if o.httpClient != nil {
+			o.auth.SetHttpClient(o.httpClient)
+		}
+		// the refresher of an auth passed with WithAuth is managed by the caller
+		if config.AuthBackgroundRefresh {
+			o.auth.StartRefresher(ctx, wg, auth.DefaultRefresherSettings)
+		}
+	}
+	if o.repo == nil {
+		o.repo = smartservicerepository.New(config, o.auth)
+		if o.httpClient != nil {
+			o.repo.SetHttpClient(o.httpClient)
+		}
+	}
+	if o.iotClient == nil {
+		o.iotClient = client.NewClient(config.DeviceRepositoryUrl, nil)
+	}
+	if o.engine == nil {
+		engine := camunda.NewClient(config)
+		if o.httpClient != nil {
+			engine.SetHttpClient(o.httpClient)
+		}
+		o.engine = engine
+	}
+	scriptRunner := middleware.NewScriptRunner(o.auth, o.iotClient)
+	handler, err := handlerfactory(Dependencies{
+		Auth:             o.auth,
+		SmartServiceRepo: o.repo,
+		IotClient:        o.iotClient,
+		Config:           config,
+		TaskLogger:       NewTaskLoggerFactory(config),
+		Engine:           o.engine,
+		ScriptRunner:     scriptRunner,
+	})
 	if err != nil {
 		return err
 	}
-	m := middleware.New(config, handler, smartServiceRepo, auth, client.NewClient(config.DeviceRepositoryUrl, nil))
-	camunda.Start(ctx, wg, config, smartServiceRepo, m)
+	var m camunda.Handler = middleware.NewWithScriptRunner(config, handler, o.repo, scriptRunner)
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		m = o.middlewares[i](m)
+	}
+	camunda.StartWithEngine(ctx, wg, config, o.engine, o.repo, m)
 	return nil
 }
+
+// withDefaultTimeout returns a copy of client with timeout, if client has no timeout
+func withDefaultTimeout(client *http.Client, timeout time.Duration) *http.Client {
+	if client.Timeout != 0 {
+		return client
+	}
+	result := *client
+	result.Timeout = timeout
+	return &result
+}
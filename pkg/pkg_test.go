@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+func TestWithLogger(t *testing.T) {
+	camundaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer camundaServer.Close()
+
+	buf := &syncBuffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	config := testConfig(camundaServer.URL)
+	config.AuthTokenSource = "unknown"
+	err := StartWithDependencies(ctx, wg, config, func(deps Dependencies) (camunda.Handler, error) {
+		deps.TaskLogger(model.CamundaExternalTask{Id: "task"}).Info("task logger")
+		return noopHandler{}, nil
+	}, WithLogger(slog.New(slog.NewTextHandler(buf, nil))))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, expected := range []string{
+		"invalid auth token source config",  //auth
+		"task logger",                       //Dependencies.TaskLogger
+		"error on ExecuteNextTasks getTask", //engine
+	} {
+		if !waitFor(func() bool { return strings.Contains(buf.String(), expected) }) {
+			t.Errorf("missing %q in log:\n%v", expected, buf.String())
+		}
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	camundaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer camundaServer.Close()
+
+	transport := &countingTransport{}
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	defer cancel()
+
+	err := StartWithDependencies(ctx, wg, testConfig(camundaServer.URL), func(deps Dependencies) (camunda.Handler, error) {
+		return noopHandler{}, nil
+	}, WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !waitFor(func() bool { return transport.count.Load() > 0 }) {
+		t.Error("engine did not use the http client")
+	}
+}
+
+func TestWithDefaultTimeout(t *testing.T) {
+	t.Run("without timeout", func(t *testing.T) {
+		client := &http.Client{}
+		result := withDefaultTimeout(client, camunda.DefaultHttpTimeout)
+		if result.Timeout != camunda.DefaultHttpTimeout {
+			t.Error(result.Timeout)
+		}
+		if client.Timeout != 0 {
+			t.Error("client of caller was changed")
+		}
+	})
+	t.Run("with timeout", func(t *testing.T) {
+		client := &http.Client{Timeout: time.Minute}
+		result := withDefaultTimeout(client, camunda.DefaultHttpTimeout)
+		if result != client {
+			t.Error("client with timeout was replaced")
+		}
+	})
+}
+
+func testConfig(camundaUrl string) configuration.Config {
+	return configuration.Config{
+		CamundaUrl:                    camundaUrl,
+		CamundaWorkerTopic:            "test",
+		CamundaWorkerWaitDurationInMs: 10,
+		CamundaFetchMaxTasks:          1,
+		CamundaLockDurationInMs:       60000,
+		LogLevel:                      "error",
+	}
+}
+
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+type noopHandler struct{}
+
+func (noopHandler) Do(task model.CamundaExternalTask) (modules []model.Module, outputs map[string]interface{}, err error) {
+	return nil, nil, nil
+}
+
+func (noopHandler) Undo(modules []model.Module, reason error) {}
+
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (this *syncBuffer) Write(p []byte) (int, error) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.buf.Write(p)
+}
+
+func (this *syncBuffer) String() string {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return this.buf.String()
+}
+
+type countingTransport struct {
+	count atomic.Int64
+}
+
+func (this *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	this.count.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -19,7 +19,6 @@ package smartservicerepository
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -39,20 +38,15 @@ func (this *SmartServiceRepository) SendWorkerError(task model.CamundaExternalTa
 	if err != nil {
 		return err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return err
 	}
 	_, _ = io.ReadAll(resp.Body)
@@ -70,20 +64,15 @@ func (this *SmartServiceRepository) SetSmartServiceError(smartServiceId string,
 	if err != nil {
 		return err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return err
 	}
 	_, _ = io.ReadAll(resp.Body)
@@ -102,20 +91,15 @@ func (this *SmartServiceRepository) SetSmartServiceModuleError(moduleId string,
 	if err != nil {
 		return err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return err
 	}
 	_, _ = io.ReadAll(resp.Body)
@@ -134,20 +118,15 @@ func (this *SmartServiceRepository) RemoveSmartServiceModuleError(moduleId strin
 	if err != nil {
 		return err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return err
 	}
 	_, _ = io.ReadAll(resp.Body)
@@ -18,7 +18,6 @@ package smartservicerepository
 
 import (
 	"encoding/json"
-	"errors"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
 	"io"
 	"net/http"
@@ -30,20 +29,15 @@ func (this *SmartServiceRepository) GetSmartServiceInstance(processInstanceId st
 	if err != nil {
 		return result, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return result, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
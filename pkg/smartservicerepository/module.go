@@ -19,7 +19,6 @@ package smartservicerepository
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -52,20 +51,15 @@ func (this *SmartServiceRepository) SendWorkerModule(module model.Module) (resul
 	if err != nil {
 		return result, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return result, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
@@ -80,14 +74,12 @@ func (this *SmartServiceRepository) UseModuleDeleteInfo(info model.ModuleDeleteI
 	if err != nil {
 		return err
 	}
+	var resp *http.Response
 	if info.UserId != "" {
-		token, err := this.auth.ExchangeUserToken(info.UserId)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Authorization", token.Jwt())
+		resp, err = this.doWithUserToken(req, info.UserId)
+	} else {
+		resp, err = this.httpClient.Do(req)
 	}
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -99,6 +91,9 @@ func (this *SmartServiceRepository) UseModuleDeleteInfo(info model.ModuleDeleteI
 		return err
 	}
 	_, _ = io.ReadAll(resp.Body)
+	if processId, ok := this.instanceOfUrl(info.Url); ok {
+		this.cache.InvalidatePrefix(instanceCachePrefix(processId))
+	}
 	return nil
 }
 
@@ -119,19 +114,14 @@ func (this *SmartServiceRepository) ListExistingModules(processInstanceId string
 	if err != nil {
 		return result, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return result, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
@@ -165,19 +155,14 @@ func (this *SmartServiceRepository) ListModules(query model.ModulQuery) (result
 	if err != nil {
 		return result, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return result, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
@@ -192,19 +177,14 @@ func (this *SmartServiceRepository) GetModule(userId string, moduleId string) (r
 	if err != nil {
 		return result, err, http.StatusInternalServerError
 	}
-	token, err := this.auth.ExchangeUserToken(userId)
-	if err != nil {
-		return result, err, http.StatusInternalServerError
-	}
-	req.Header.Set("Authorization", token.Jwt())
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithUserToken(req, userId)
 	if err != nil {
 		return result, err, http.StatusInternalServerError
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err, resp.StatusCode
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
@@ -0,0 +1,32 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package smartservicerepository
+
+// ResponseError is returned if the smart-service-repository responds with an error status code.
+// Errors of the Auth are returned unchanged (see auth.ErrAuth), so both cases can be told apart.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (this *ResponseError) Error() string {
+	return this.Body
+}
+
+func newResponseError(statusCode int, body []byte) error {
+	return &ResponseError{StatusCode: statusCode, Body: string(body)}
+}
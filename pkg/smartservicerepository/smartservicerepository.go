@@ -17,15 +17,20 @@
 package smartservicerepository
 
 import (
+	"io"
+	"net/http"
+	"time"
+
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 )
 
 type SmartServiceRepository struct {
-	config configuration.Config
-	auth   Auth
-	cache  *cache.Cache
+	config     configuration.Config
+	auth       Auth
+	cache      *cache.Typed[string, string]
+	httpClient *http.Client
 }
 
 type Auth interface {
@@ -33,6 +38,87 @@ type Auth interface {
 	ExchangeUserToken(userid string) (token auth.Token, err error)
 }
 
+// TokenInvalidator is optionally implemented by Auth (e.g. *auth.Auth);
+// if it is, requests rejected with 401 are retried once with a fresh token
+type TokenInvalidator interface {
+	InvalidateToken(token auth.Token)
+	InvalidateUserToken(userid string)
+}
+
 func New(config configuration.Config, auth Auth) *SmartServiceRepository {
-	return &SmartServiceRepository{config: config, auth: auth, cache: cache.NewCache(30)}
+	backend, err := cache.NewBackend(config)
+	if err != nil {
+		config.GetLogger().Error("invalid cache backend config, smart-service-repository results are only cached in-process", "error", err)
+	}
+	return &SmartServiceRepository{
+		config:     config,
+		auth:       auth,
+		cache:      cache.NewTyped[string, string](30*time.Second, cache.WithBackend(backend, "smart-service-repository/"), cache.WithLogger(config.GetLogger())),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// CacheStats returns the counters of the cache used by GetInstanceUser
+func (this *SmartServiceRepository) CacheStats() cache.Stats {
+	return this.cache.Stats()
+}
+
+// SetHttpClient replaces the http.Client used for requests to the smart-service-repository and module delete urls
+func (this *SmartServiceRepository) SetHttpClient(client *http.Client) *SmartServiceRepository {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	this.httpClient = client
+	return this
+}
+
+// doWithClientToken sends req with the client token (Auth.Ensure)
+func (this *SmartServiceRepository) doWithClientToken(req *http.Request) (*http.Response, error) {
+	return this.doWithToken(req, this.auth.Ensure, func(invalidator TokenInvalidator, token auth.Token) {
+		invalidator.InvalidateToken(token)
+	})
+}
+
+// doWithUserToken sends req with an exchanged token of userId
+func (this *SmartServiceRepository) doWithUserToken(req *http.Request, userId string) (*http.Response, error) {
+	return this.doWithToken(req, func() (auth.Token, error) {
+		return this.auth.ExchangeUserToken(userId)
+	}, func(invalidator TokenInvalidator, token auth.Token) {
+		invalidator.InvalidateUserToken(userId)
+	})
+}
+
+// doWithToken sets the Authorization header; if the response is 401, the token is invalidated
+// and the request is retried exactly once with a fresh token
+func (this *SmartServiceRepository) doWithToken(req *http.Request, getToken func() (auth.Token, error), invalidate func(invalidator TokenInvalidator, token auth.Token)) (*http.Response, error) {
+	token, err := getToken()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", token.Jwt())
+	resp, err := this.httpClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	invalidator, ok := this.auth.(TokenInvalidator)
+	if !ok || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	this.config.GetLogger().Info("request rejected with 401; retry with new token", "method", req.Method, "url", req.URL.String())
+	invalidate(invalidator, token)
+	token, err = getToken()
+	if err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", token.Jwt())
+	return this.httpClient.Do(retry)
 }
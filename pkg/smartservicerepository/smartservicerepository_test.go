@@ -18,12 +18,18 @@ package smartservicerepository
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authmock"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
 )
@@ -298,3 +304,237 @@ func TestListExistingModulesFilterByKey(t *testing.T) {
 		t.Error(result)
 	}
 }
+
+type AuthErrorMock struct{}
+
+func (this AuthErrorMock) Ensure() (token auth.Token, err error) {
+	return token, auth.ErrInvalidClientCredentials
+}
+
+func (this AuthErrorMock) ExchangeUserToken(userid string) (token auth.Token, err error) {
+	return token, auth.ErrExchangeForbidden
+}
+
+func TestSendWorkerErrorFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := configuration.Config{SmartServiceRepositoryUrl: server.URL}
+	task := model.CamundaExternalTask{ProcessInstanceId: "my-process-instance-id"}
+
+	err := New(config, AuthErrorMock{}).SendWorkerError(task, errors.New("test"))
+	if !errors.Is(err, auth.ErrAuth) {
+		t.Error(err)
+	}
+
+	err = New(config, AuthMock).SendWorkerError(task, errors.New("test"))
+	responseErr := &ResponseError{}
+	if errors.Is(err, auth.ErrAuth) || !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusNotFound {
+		t.Error(err)
+	}
+}
+
+func TestRetryUnauthorized(t *testing.T) {
+	mux := sync.Mutex{}
+	requests := []string{}
+	rejected := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		mux.Lock()
+		requests = append(requests, request.Header.Get("Authorization")+" "+string(body))
+		reject := rejected[request.Header.Get("Authorization")]
+		mux.Unlock()
+		if reject {
+			http.Error(writer, "token expired", http.StatusUnauthorized)
+			return
+		}
+		if request.Method == http.MethodGet {
+			json.NewEncoder(writer).Encode(model.SmartServiceModule{})
+		}
+	}))
+	defer server.Close()
+	config := configuration.Config{SmartServiceRepositoryUrl: server.URL}
+
+	reset := func(rejectedTokens ...string) {
+		mux.Lock()
+		defer mux.Unlock()
+		requests = []string{}
+		rejected = map[string]bool{}
+		for _, token := range rejectedTokens {
+			rejected[token] = true
+		}
+	}
+	getRequests := func() []string {
+		mux.Lock()
+		defer mux.Unlock()
+		return slices.Clone(requests)
+	}
+
+	t.Run("client token with body", func(t *testing.T) {
+		reset("token-0")
+		a := &authmock.RotatingAuth{}
+		err := New(config, a).SetVariables("pid", map[string]interface{}{"foo": "bar"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		expected := []string{"token-0 {\"foo\":\"bar\"}\n", "token-1 {\"foo\":\"bar\"}\n"}
+		if !reflect.DeepEqual(getRequests(), expected) {
+			t.Errorf("%#v", getRequests())
+		}
+		if !reflect.DeepEqual(a.Invalidations(), []string{"client:token-0"}) {
+			t.Error(a.Invalidations())
+		}
+	})
+
+	t.Run("user token", func(t *testing.T) {
+		reset("token-0")
+		a := &authmock.RotatingAuth{}
+		_, err, code := New(config, a).GetModule("user", "module")
+		if err != nil || code != http.StatusOK {
+			t.Error(err, code)
+			return
+		}
+		if !reflect.DeepEqual(a.Invalidations(), []string{"user:user"}) || len(getRequests()) != 2 {
+			t.Error(a.Invalidations(), getRequests())
+		}
+	})
+
+	t.Run("retry exactly once", func(t *testing.T) {
+		reset("token-0", "token-1", "token-2")
+		a := &authmock.RotatingAuth{}
+		err := New(config, a).SetVariables("pid", map[string]interface{}{"foo": "bar"})
+		responseErr := &ResponseError{}
+		if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusUnauthorized {
+			t.Error(err)
+		}
+		if len(getRequests()) != 2 {
+			t.Error(getRequests())
+		}
+	})
+
+	t.Run("auth without invalidation", func(t *testing.T) {
+		reset(string(AuthMock))
+		err := New(config, AuthMock).SetVariables("pid", map[string]interface{}{"foo": "bar"})
+		if err == nil || len(getRequests()) != 1 {
+			t.Error(err, getRequests())
+		}
+	})
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	mux := sync.Mutex{}
+	userRequests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodGet {
+			mux.Lock()
+			userRequests[request.URL.Path]++
+			mux.Unlock()
+			json.NewEncoder(writer).Encode("user")
+		}
+	}))
+	defer server.Close()
+	repo := New(configuration.Config{SmartServiceRepositoryUrl: server.URL}, AuthMock)
+
+	getUsers := func() {
+		for _, id := range []string{"p1", "p2"} {
+			if userId, err := repo.GetInstanceUser(id); err != nil || userId != "user" {
+				t.Error(err, userId)
+			}
+		}
+	}
+	requests := func() (p1 int, p2 int) {
+		mux.Lock()
+		defer mux.Unlock()
+		return userRequests["/instances-by-process-id/p1/user-id"], userRequests["/instances-by-process-id/p2/user-id"]
+	}
+
+	getUsers()
+	getUsers()
+	if p1, p2 := requests(); p1 != 1 || p2 != 1 {
+		t.Error("expected cached users", p1, p2)
+	}
+
+	// variables are not cached
+	err := repo.SetVariables("p1", map[string]interface{}{"foo": "bar"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	getUsers()
+	if p1, p2 := requests(); p1 != 1 || p2 != 1 {
+		t.Error("SetVariables should not invalidate the cached users", p1, p2)
+	}
+
+	err = repo.UseModuleDeleteInfo(model.ModuleDeleteInfo{Url: server.URL + "/some/resource"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	getUsers()
+	if p1, p2 := requests(); p1 != 1 || p2 != 1 {
+		t.Error("deleting resources of other services should not invalidate the cache", p1, p2)
+	}
+
+	err = repo.UseModuleDeleteInfo(model.ModuleDeleteInfo{Url: server.URL + "/instances-by-process-id/p2/modules/m1"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	getUsers()
+	if p1, p2 := requests(); p1 != 1 || p2 != 2 {
+		t.Error("UseModuleDeleteInfo should only invalidate the cache of the affected instance", p1, p2)
+	}
+}
+
+func TestGetInstanceUserUnavailable(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		repo := New(configuration.Config{SmartServiceRepositoryUrl: "http://localhost:1"}, AuthMock)
+		for i := 0; i < 2; i++ {
+			if _, err := repo.GetInstanceUser("p1"); err == nil {
+				t.Error("expected error")
+			}
+		}
+		if stats := repo.CacheStats(); stats.NegativeHits != 1 || stats.LoadErrors != 1 {
+			t.Error("unreachable repository should be remembered", stats)
+		}
+	})
+
+	t.Run("5xx", func(t *testing.T) {
+		requests := atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requests.Add(1)
+			http.Error(writer, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		repo := New(configuration.Config{SmartServiceRepositoryUrl: server.URL}, AuthMock)
+		for i := 0; i < 2; i++ {
+			if _, err := repo.GetInstanceUser("p1"); err == nil {
+				t.Error("expected error")
+			}
+		}
+		if count := requests.Load(); count != 1 {
+			t.Error("5xx responses should be remembered", count)
+		}
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		requests := atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requests.Add(1)
+			http.Error(writer, "bad request", http.StatusBadRequest)
+		}))
+		defer server.Close()
+		repo := New(configuration.Config{SmartServiceRepositoryUrl: server.URL}, AuthMock)
+		for i := 0; i < 2; i++ {
+			if _, err := repo.GetInstanceUser("p1"); err == nil {
+				t.Error("expected error")
+			}
+		}
+		if count := requests.Load(); count != 2 {
+			t.Error("4xx responses should not be remembered", count)
+		}
+	})
+}
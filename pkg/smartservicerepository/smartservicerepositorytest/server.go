@@ -0,0 +1,407 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package smartservicerepositorytest provides a stateful in-memory fake of the smart-service-repository api used by smartservicerepository.SmartServiceRepository.
+// Set configuration.Config.SmartServiceRepositoryUrl to Server.URL.
+package smartservicerepositorytest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+type Server struct {
+	URL string
+
+	server    *httptest.Server
+	mux       sync.Mutex
+	instances map[string]model.SmartServiceInstance //process-instance-id -> instance
+	modules   []model.SmartServiceModule
+	variables map[string]map[string]interface{} //instance-id -> variables
+	errors    []Error
+	requests  []Request
+}
+
+// Request is a received request; the body is already read
+type Request struct {
+	Method        string
+	Path          string
+	Query         string
+	Authorization string
+	Body          []byte
+}
+
+// Error is an error reported by the worker
+type Error struct {
+	ProcessInstanceId string //set for /instances-by-process-id/{id}/error
+	InstanceId        string //set for /instances-by-process-id/{id}/error and /instances/{id}/error
+	ModuleId          string //set for /modules/{id}/error
+	Message           string
+}
+
+// State is a copy of the stored data
+type State struct {
+	Instances map[string]model.SmartServiceInstance //process-instance-id -> instance
+	Modules   []model.SmartServiceModule
+	Variables map[string]map[string]interface{} //instance-id -> variables
+	Errors    []Error
+}
+
+func NewServer() *Server {
+	result := &Server{
+		instances: map[string]model.SmartServiceInstance{},
+		variables: map[string]map[string]interface{}{},
+	}
+	result.server = httptest.NewServer(result.router())
+	result.URL = result.server.URL
+	return result
+}
+
+func (this *Server) Close() {
+	this.server.Close()
+}
+
+// AddInstance stores the instance for the process instance id; variables are optional
+func (this *Server) AddInstance(processInstanceId string, instance model.SmartServiceInstance, variables map[string]interface{}) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.instances[processInstanceId] = instance
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	this.variables[instance.Id] = maps.Clone(variables)
+}
+
+// AddModule stores or replaces (by id) a module
+func (this *Server) AddModule(module model.SmartServiceModule) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.putModule(module)
+}
+
+func (this *Server) State() (result State) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result.Instances = maps.Clone(this.instances)
+	result.Modules = slices.Clone(this.modules)
+	result.Variables = map[string]map[string]interface{}{}
+	for key, value := range this.variables {
+		result.Variables[key] = maps.Clone(value)
+	}
+	result.Errors = slices.Clone(this.errors)
+	return result
+}
+
+func (this *Server) Requests() []Request {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.requests)
+}
+
+func (this *Server) router() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /instances-by-process-id/{id}", this.getInstance)
+	mux.HandleFunc("GET /instances-by-process-id/{id}/user-id", this.getInstanceUser)
+	mux.HandleFunc("GET /instances-by-process-id/{id}/variables-map", this.getVariables)
+	mux.HandleFunc("PUT /instances-by-process-id/{id}/variables-map", this.setVariables)
+	mux.HandleFunc("PUT /instances-by-process-id/{id}/error", this.setProcessInstanceError)
+	mux.HandleFunc("GET /instances-by-process-id/{id}/modules", this.listInstanceModules)
+	mux.HandleFunc("PUT /instances-by-process-id/{id}/modules/{moduleId}", this.setModule)
+	mux.HandleFunc("PUT /instances/{id}/error", this.setInstanceError)
+	mux.HandleFunc("GET /modules", this.listModules)
+	mux.HandleFunc("GET /modules/{id}", this.getModule)
+	mux.HandleFunc("PUT /modules/{id}/error", this.setModuleError)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		request.Body = io.NopCloser(strings.NewReader(string(body)))
+		this.mux.Lock()
+		this.requests = append(this.requests, Request{
+			Method:        request.Method,
+			Path:          request.URL.Path,
+			Query:         request.URL.RawQuery,
+			Authorization: request.Header.Get("Authorization"),
+			Body:          body,
+		})
+		this.mux.Unlock()
+		mux.ServeHTTP(writer, request)
+	})
+}
+
+func (this *Server) getInstance(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	writeJson(writer, instance)
+}
+
+func (this *Server) getInstanceUser(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	writeJson(writer, instance.UserId)
+}
+
+func (this *Server) getVariables(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	variables := this.variables[instance.Id]
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	writeJson(writer, variables)
+}
+
+func (this *Server) setVariables(writer http.ResponseWriter, request *http.Request) {
+	changes := map[string]interface{}{}
+	if !decode(writer, request, &changes) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	if this.variables[instance.Id] == nil {
+		this.variables[instance.Id] = map[string]interface{}{}
+	}
+	maps.Copy(this.variables[instance.Id], changes)
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (this *Server) setProcessInstanceError(writer http.ResponseWriter, request *http.Request) {
+	var msg string
+	if !decode(writer, request, &msg) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	processInstanceId := request.PathValue("id")
+	instance, ok := this.instances[processInstanceId]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	instance.Error = msg
+	this.instances[processInstanceId] = instance
+	this.errors = append(this.errors, Error{ProcessInstanceId: processInstanceId, InstanceId: instance.Id, Message: msg})
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (this *Server) setInstanceError(writer http.ResponseWriter, request *http.Request) {
+	var msg string
+	if !decode(writer, request, &msg) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instanceId := request.PathValue("id")
+	found := false
+	for processInstanceId, instance := range this.instances {
+		if instance.Id == instanceId {
+			instance.Error = msg
+			this.instances[processInstanceId] = instance
+			found = true
+		}
+	}
+	if !found {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	this.errors = append(this.errors, Error{InstanceId: instanceId, Message: msg})
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (this *Server) setModuleError(writer http.ResponseWriter, request *http.Request) {
+	var msg string
+	if !decode(writer, request, &msg) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	moduleId := request.PathValue("id")
+	index := slices.IndexFunc(this.modules, func(module model.SmartServiceModule) bool {
+		return module.Id == moduleId
+	})
+	if index < 0 {
+		http.Error(writer, "module not found", http.StatusNotFound)
+		return
+	}
+	this.modules[index].Error = msg
+	this.errors = append(this.errors, Error{ModuleId: moduleId, Message: msg})
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (this *Server) setModule(writer http.ResponseWriter, request *http.Request) {
+	init := model.SmartServiceModuleInit{}
+	if !decode(writer, request, &init) {
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	module := model.SmartServiceModule{
+		SmartServiceModuleBase: model.SmartServiceModuleBase{
+			Id:         request.PathValue("moduleId"),
+			UserId:     instance.UserId,
+			InstanceId: instance.Id,
+			DesignId:   instance.DesignId,
+			ReleaseId:  instance.ReleaseId,
+			LastUpdate: time.Now().Unix(),
+		},
+		SmartServiceModuleInit: init,
+	}
+	this.putModule(module)
+	writeJson(writer, module)
+}
+
+func (this *Server) listInstanceModules(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	instance, ok := this.instances[request.PathValue("id")]
+	if !ok {
+		http.Error(writer, "instance not found", http.StatusNotFound)
+		return
+	}
+	result := []model.SmartServiceModule{}
+	for _, module := range this.modules {
+		if module.InstanceId == instance.Id && matchesQuery(module, request) {
+			result = append(result, module)
+		}
+	}
+	writeJson(writer, result)
+}
+
+func (this *Server) listModules(writer http.ResponseWriter, request *http.Request) {
+	limit, offset, err := limitAndOffset(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	result := []model.SmartServiceModule{}
+	for _, module := range this.modules {
+		if matchesQuery(module, request) {
+			result = append(result, module)
+		}
+	}
+	result = result[min(offset, len(result)):]
+	if limit > 0 {
+		result = result[:min(limit, len(result))]
+	}
+	writeJson(writer, result)
+}
+
+func (this *Server) getModule(writer http.ResponseWriter, request *http.Request) {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	index := slices.IndexFunc(this.modules, func(module model.SmartServiceModule) bool {
+		return module.Id == request.PathValue("id")
+	})
+	if index < 0 {
+		http.Error(writer, "module not found", http.StatusNotFound)
+		return
+	}
+	writeJson(writer, this.modules[index])
+}
+
+// putModule expects this.mux to be locked
+func (this *Server) putModule(module model.SmartServiceModule) {
+	index := slices.IndexFunc(this.modules, func(existing model.SmartServiceModule) bool {
+		return existing.Id == module.Id
+	})
+	if index < 0 {
+		this.modules = append(this.modules, module)
+	} else {
+		this.modules[index] = module
+	}
+}
+
+// matchesQuery handles the "key" and "module_type" query parameters used by ListModules and ListExistingModules
+func matchesQuery(module model.SmartServiceModule, request *http.Request) bool {
+	query := request.URL.Query()
+	if query.Has("key") && !slices.Contains(module.Keys, query.Get("key")) {
+		return false
+	}
+	if query.Has("module_type") && module.ModuleType != query.Get("module_type") {
+		return false
+	}
+	return true
+}
+
+func limitAndOffset(request *http.Request) (limit int, offset int, err error) {
+	query := request.URL.Query()
+	if query.Has("limit") {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil {
+			return
+		}
+	}
+	if query.Has("offset") {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil {
+			return
+		}
+	}
+	if limit < 0 || offset < 0 {
+		err = errors.New("limit and offset must not be negative")
+	}
+	return
+}
+
+func decode(writer http.ResponseWriter, request *http.Request, result interface{}) bool {
+	err := json.NewDecoder(request.Body).Decode(result)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJson(writer http.ResponseWriter, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(value)
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package smartservicerepositorytest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/smartservicerepository"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddInstance("pid", model.SmartServiceInstance{Id: "instance", UserId: "user", DesignId: "design", ReleaseId: "release"}, map[string]interface{}{"v1": "foo"})
+	server.AddModule(model.SmartServiceModule{
+		SmartServiceModuleBase: model.SmartServiceModuleBase{Id: "other", InstanceId: "other-instance"},
+		SmartServiceModuleInit: model.SmartServiceModuleInit{ModuleType: "mt", Keys: []string{"k1"}},
+	})
+
+	repo := smartservicerepository.New(configuration.Config{SmartServiceRepositoryUrl: server.URL, CamundaWorkerTopic: "topic", LogLevel: "error"}, authMock{})
+
+	instance, err := repo.GetSmartServiceInstance("pid")
+	if err != nil || instance.Id != "instance" {
+		t.Error(err, instance)
+		return
+	}
+	userId, err := repo.GetInstanceUser("pid")
+	if err != nil || userId != "user" {
+		t.Error(err, userId)
+		return
+	}
+	err = repo.SetVariables("pid", map[string]interface{}{"v2": "bar"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	variables, err := repo.GetVariables("pid")
+	if err != nil || !reflect.DeepEqual(variables, map[string]interface{}{"v1": "foo", "v2": "bar"}) {
+		t.Error(err, variables)
+		return
+	}
+	_, err = repo.SendWorkerModules([]model.Module{
+		{Id: "m1", ProcesInstanceId: "pid", SmartServiceModuleInit: model.SmartServiceModuleInit{ModuleType: "mt", Keys: []string{"k1"}}},
+		{Id: "m2", ProcesInstanceId: "pid", SmartServiceModuleInit: model.SmartServiceModuleInit{ModuleType: "other", Keys: []string{"k2"}}},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	mt := "mt"
+	modules, err := repo.ListExistingModules("pid", model.ModulQuery{TypeFilter: &mt})
+	if err != nil || len(modules) != 1 || modules[0].Id != "m1" || modules[0].UserId != "user" {
+		t.Error(err, modules)
+		return
+	}
+	k1 := "k1"
+	modules, err = repo.ListModules(model.ModulQuery{KeyFilter: &k1, Limit: 1, Offset: 1})
+	if err != nil || len(modules) != 1 || modules[0].Id != "m1" {
+		t.Error(err, modules)
+		return
+	}
+	module, err, _ := repo.GetModule("user", "m2")
+	if err != nil || module.ModuleType != "other" {
+		t.Error(err, module)
+		return
+	}
+	_, err, code := repo.GetModule("user", "unknown")
+	if err == nil || code != 404 {
+		t.Error(err, code)
+		return
+	}
+
+	err = repo.SendWorkerError(model.CamundaExternalTask{ProcessInstanceId: "pid"}, errors.New("test"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = repo.SetSmartServiceModuleError("m2", errors.New("module-test"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	state := server.State()
+	if state.Instances["pid"].Error != "topic: test" {
+		t.Error(state.Instances)
+	}
+	if len(state.Modules) != 3 || state.Modules[2].Error != "topic: module-test" {
+		t.Error(state.Modules)
+	}
+	if !reflect.DeepEqual(state.Errors, []Error{
+		{ProcessInstanceId: "pid", InstanceId: "instance", Message: "topic: test"},
+		{ModuleId: "m2", Message: "topic: module-test"},
+	}) {
+		t.Error(state.Errors)
+	}
+	if requests := server.Requests(); len(requests) == 0 || requests[0].Authorization != "token" {
+		t.Error(requests)
+	}
+}
+
+type authMock struct{}
+
+func (this authMock) Ensure() (token auth.Token, err error) {
+	return auth.Token{Token: "token"}, nil
+}
+
+func (this authMock) ExchangeUserToken(userid string) (token auth.Token, err error) {
+	return auth.Token{Token: "token"}, nil
+}
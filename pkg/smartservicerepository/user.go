@@ -20,16 +20,39 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/cache"
 )
 
+// instanceCachePrefix is the prefix of all cache keys of a process instance, to invalidate them after writes
+func instanceCachePrefix(processId string) string {
+	return "instances-by-process-id/" + processId + "/"
+}
+
+// instanceOfUrl returns the process instance id, if rawUrl points to a resource of an instance in the smart-service-repository
+func (this *SmartServiceRepository) instanceOfUrl(rawUrl string) (processId string, ok bool) {
+	rest, ok := strings.CutPrefix(rawUrl, this.config.SmartServiceRepositoryUrl+"/instances-by-process-id/")
+	if !ok {
+		return "", false
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "/")
+	processId, err := url.PathUnescape(rest)
+	return processId, err == nil && processId != ""
+}
+
 func (this *SmartServiceRepository) GetInstanceUser(instanceId string) (userId string, err error) {
-	err = this.cache.Use("instances-by-process-id/"+instanceId+"/user-id", 10*time.Second, func() (interface{}, error) {
+	return this.cache.Use(instanceCachePrefix(instanceId)+"user-id", 10*time.Second, func() (string, error) {
 		return this.getInstanceUser(instanceId)
-	}, &userId)
-	return userId, nil
+	}, cache.WithStaleWhileRevalidate(time.Minute),
+		cache.WithNegativeCaching(2*time.Second, isNotFound),
+		cache.WithNegativeCaching(time.Second, isUnavailable))
 }
 
 func (this *SmartServiceRepository) getInstanceUser(instanceId string) (userId string, err error) {
@@ -37,20 +60,15 @@ func (this *SmartServiceRepository) getInstanceUser(instanceId string) (userId s
 	if err != nil {
 		return userId, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return userId, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return userId, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return userId, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&userId)
@@ -59,3 +77,19 @@ func (this *SmartServiceRepository) getInstanceUser(instanceId string) (userId s
 	}
 	return userId, nil
 }
+
+func isNotFound(err error) bool {
+	responseErr := &ResponseError{}
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+// isUnavailable returns true for transport errors and 5xx responses of the smart-service-repository or the auth server;
+// they are remembered shortly, so that an outage is not hit by every task
+func isUnavailable(err error) bool {
+	responseErr := &ResponseError{}
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, auth.ErrAuthServerUnreachable)
+}
@@ -19,7 +19,6 @@ package smartservicerepository
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -31,20 +30,15 @@ func (this *SmartServiceRepository) GetVariables(processId string) (result map[s
 	if err != nil {
 		return result, err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return result, err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return result, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return result, err
 	}
 	err = json.NewDecoder(resp.Body).Decode(&result)
@@ -65,20 +59,15 @@ func (this *SmartServiceRepository) SetVariables(processId string, variableChang
 	if err != nil {
 		return err
 	}
-	token, err := this.auth.Ensure()
-	if err != nil {
-		return err
-	}
-	req.Header.Set("Authorization", token.Jwt())
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := this.doWithClientToken(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		temp, _ := io.ReadAll(resp.Body)
-		err = errors.New(string(temp))
+		err = newResponseError(resp.StatusCode, temp)
 		return err
 	}
 	return nil
@@ -21,25 +21,33 @@ import (
 	"github.com/SENERGY-Platform/device-repository/lib/client"
 	devicemodel "github.com/SENERGY-Platform/device-repository/lib/model"
 	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+	"net/http"
 )
 
 var ErrNoDeviceOrGroupSelection = errors.New("expect device or group selection")
 
 func GetDevicesWithService(iotClient client.Interface, token string, entity model.IotOption, criteria []devicemodel.FilterCriteria) (result []model.IotOption, err error) {
+	result, err, _ = GetDevicesWithServiceWithCode(iotClient, token, entity, criteria)
+	return result, err
+}
+
+// GetDevicesWithServiceWithCode is like GetDevicesWithService, but also returns the status code of a failed device-repository request
+// (e.g. http.StatusUnauthorized if token was rejected); http.StatusOK on success
+func GetDevicesWithServiceWithCode(iotClient client.Interface, token string, entity model.IotOption, criteria []devicemodel.FilterCriteria) (result []model.IotOption, err error, code int) {
 	if entity.DeviceSelection != nil {
 		if entity.DeviceSelection.ServiceId != nil {
-			return []model.IotOption{entity}, nil //nothing to do
+			return []model.IotOption{entity}, nil, http.StatusOK //nothing to do
 		}
 		return getDevicesWithService(iotClient, token, []string{entity.DeviceSelection.DeviceId}, criteria)
 	}
 	if entity.DeviceGroupSelection != nil {
-		groupDevices, err, _ := iotClient.ListHubDeviceIds(entity.DeviceGroupSelection.Id, token, devicemodel.READ, false)
+		groupDevices, err, code := iotClient.ListHubDeviceIds(entity.DeviceGroupSelection.Id, token, devicemodel.READ, false)
 		if err != nil {
-			return result, err
+			return result, err, code
 		}
 		return getDevicesWithService(iotClient, token, groupDevices, criteria)
 	}
-	return result, ErrNoDeviceOrGroupSelection
+	return result, ErrNoDeviceOrGroupSelection, http.StatusBadRequest
 }
 
 // GroupIotOptionsByDevice groups a list of model.IotOption by their device id
@@ -71,15 +79,15 @@ func GroupIotOptionsByService(options []model.IotOption) (result map[string][]mo
 	return result
 }
 
-func getDevicesWithService(iotClient client.Interface, token string, deviceIds []string, criteria []devicemodel.FilterCriteria) (result []model.IotOption, err error) {
-	dtSelectables, err, _ := iotClient.GetDeviceTypeSelectablesV2(criteria, "", true, true)
+func getDevicesWithService(iotClient client.Interface, token string, deviceIds []string, criteria []devicemodel.FilterCriteria) (result []model.IotOption, err error, code int) {
+	dtSelectables, err, code := iotClient.GetDeviceTypeSelectablesV2(criteria, "", true, true)
 	if err != nil {
-		return result, err
+		return result, err, code
 	}
 	for _, deviceId := range deviceIds {
-		device, err, _ := iotClient.ReadDevice(deviceId, token, devicemodel.READ)
+		device, err, code := iotClient.ReadDevice(deviceId, token, devicemodel.READ)
 		if err != nil {
-			return result, err
+			return result, err, code
 		}
 		for _, dtSelectable := range dtSelectables {
 			if device.DeviceTypeId == dtSelectable.DeviceTypeId {
@@ -116,5 +124,5 @@ func getDevicesWithService(iotClient client.Interface, token string, deviceIds [
 			}
 		}
 	}
-	return result, nil
+	return result, nil, http.StatusOK
 }
@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package workertest runs a worker with pkg.StartWithDependencies against local fakes
+// for the process-engine (camundatest), the smart-service-repository (smartservicerepositorytest),
+// the OpenID Connect server (authtest) and the device-repository (client.NewTestClient).
+package workertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SENERGY-Platform/device-repository/lib/client"
+	"github.com/SENERGY-Platform/device-repository/lib/database"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/auth/authtest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda/camundatest"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/smartservicerepository/smartservicerepositorytest"
+)
+
+const ClientId = "workertest"
+const ClientSecret = "workertest-secret"
+
+var ErrTimeout = errors.New("timeout while waiting for the worker to finish the task")
+
+type Harness struct {
+	Config     configuration.Config
+	Camunda    *camundatest.Server
+	Repository *smartservicerepositorytest.Server
+	Auth       *authtest.Server
+	IotClient  client.Interface
+	IotDb      database.Database
+
+	// UserId is the owner of the smart-service instances created by RunTask
+	UserId string
+	// Timeout limits how long RunTask waits for the worker
+	Timeout time.Duration
+
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	mux     sync.Mutex
+	count   int
+	undoRec *undoRecorder
+}
+
+// Result describes what the worker did with a task
+type Result struct {
+	ProcessInstanceId string
+	// Completed is true if the task was completed in the engine
+	Completed bool
+	// Outputs are the variables sent with the completion
+	Outputs map[string]interface{}
+	// ProcessInstanceDeleted is true if the worker stopped the process instance (after an error)
+	ProcessInstanceDeleted bool
+	// Modules are the modules stored for the smart-service instance
+	Modules []model.SmartServiceModule
+	// Variables are the smart-service variables after the task
+	Variables map[string]interface{}
+	// VariableChanges are the variables that were added or changed by the task
+	VariableChanges map[string]interface{}
+	// Errors are the errors reported to the smart-service-repository for the instance
+	Errors []smartservicerepositorytest.Error
+	// Undos are the calls of camunda.Handler.Undo for the task
+	Undos []Undo
+}
+
+type Undo struct {
+	Modules []model.Module
+	Reason  error
+}
+
+// Start starts all fakes and the worker; the worker configuration is derived from config with the urls and credentials of the fakes.
+// Unset camunda worker settings get test friendly defaults; the resulting configuration is checked with configuration.Validate.
+// Stop the harness with Close.
+func Start(config configuration.Config, handlerfactory pkg.DependencyHandlerFactory, opts ...pkg.Option) (result *Harness, err error) {
+	result = &Harness{UserId: "user", Timeout: 10 * time.Second, wg: &sync.WaitGroup{}, undoRec: &undoRecorder{}}
+	defer func() {
+		if err != nil {
+			result.closeServers()
+		}
+	}()
+	result.Camunda = camundatest.NewServer()
+	result.Repository = smartservicerepositorytest.NewServer()
+	result.Auth, err = authtest.NewServer(ClientId, ClientSecret)
+	if err != nil {
+		return result, err
+	}
+	result.Auth.SetRoles(result.Auth.ClientSubject(), "admin")
+	result.IotClient, result.IotDb, err = client.NewTestClient()
+	if err != nil {
+		return result, err
+	}
+
+	config.CamundaUrl = result.Camunda.URL
+	config.SmartServiceRepositoryUrl = result.Repository.URL
+	config.AuthEndpoint = result.Auth.URL
+	config.AuthClientId = ClientId
+	config.AuthClientSecret = ClientSecret
+	if config.CamundaWorkerId == "" {
+		config.CamundaWorkerId = "workertest"
+	}
+	if config.CamundaWorkerTopic == "" {
+		config.CamundaWorkerTopic = "workertest"
+	}
+	if config.CamundaLockDurationInMs == 0 {
+		config.CamundaLockDurationInMs = 60000
+	}
+	if config.CamundaWorkerWaitDurationInMs == 0 {
+		config.CamundaWorkerWaitDurationInMs = 10
+	}
+	if config.CamundaFetchMaxTasks == 0 {
+		config.CamundaFetchMaxTasks = 1
+	}
+	if config.TokenCacheDefaultExpirationInSeconds == 0 {
+		config.TokenCacheDefaultExpirationInSeconds = 60
+	}
+	err = configuration.Validate(config)
+	if err != nil {
+		return result, err
+	}
+	result.Config = config
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result.cancel = cancel
+	opts = append([]pkg.Option{pkg.WithIotClient(result.IotClient)}, opts...)
+	opts = append(opts, pkg.WithMiddlewares(result.undoRec.middleware))
+	err = pkg.StartWithDependencies(ctx, result.wg, config, handlerfactory, opts...)
+	if err != nil {
+		cancel()
+		return result, err
+	}
+	return result, nil
+}
+
+// Close stops the worker and the fakes
+func (this *Harness) Close() {
+	this.cancel()
+	this.wg.Wait()
+	this.closeServers()
+}
+
+func (this *Harness) closeServers() {
+	if this.Camunda != nil {
+		this.Camunda.Close()
+	}
+	if this.Repository != nil {
+		this.Repository.Close()
+	}
+	if this.Auth != nil {
+		this.Auth.Close()
+	}
+}
+
+// RunTask creates a smart-service instance with the variables, queues a task with the inputs for the worker topic
+// and waits until the worker has completed the task or stopped the process instance
+func (this *Harness) RunTask(inputs map[string]interface{}, variables map[string]interface{}) (result Result, err error) {
+	this.mux.Lock()
+	this.count++
+	id := strconv.Itoa(this.count)
+	this.mux.Unlock()
+
+	result.ProcessInstanceId = "process-instance-" + id
+	instance := model.SmartServiceInstance{
+		Id:        "instance-" + id,
+		UserId:    this.UserId,
+		DesignId:  "design-" + id,
+		ReleaseId: "release-" + id,
+	}
+	this.Repository.AddInstance(result.ProcessInstanceId, instance, variables)
+
+	task := model.CamundaExternalTask{
+		Id:                "task-" + id,
+		ProcessInstanceId: result.ProcessInstanceId,
+		Variables:         map[string]model.CamundaVariable{},
+		Retries:           3,
+	}
+	for key, value := range inputs {
+		task.Variables[key] = model.CamundaVariable{Value: value}
+	}
+	this.Camunda.AddTask(this.Config.CamundaWorkerTopic, task)
+
+	finished := this.Camunda.WaitFor(this.Timeout, func(server *camundatest.Server) bool {
+		return !slices.ContainsFunc(server.PendingTasks(this.Config.CamundaWorkerTopic), func(pending model.CamundaExternalTask) bool {
+			return pending.Id == task.Id
+		})
+	})
+
+	for _, completion := range this.Camunda.Completions() {
+		if completion.Task.Id == task.Id {
+			result.Completed = true
+			result.Outputs = map[string]interface{}{}
+			for key, value := range completion.Request.Variables {
+				result.Outputs[key] = value.Value
+			}
+		}
+	}
+	result.ProcessInstanceDeleted = slices.Contains(this.Camunda.DeletedProcessInstances(), result.ProcessInstanceId)
+
+	state := this.Repository.State()
+	for _, module := range state.Modules {
+		if module.InstanceId == instance.Id {
+			result.Modules = append(result.Modules, module)
+		}
+	}
+	result.Variables = state.Variables[instance.Id]
+	result.VariableChanges = map[string]interface{}{}
+	for key, value := range result.Variables {
+		if prev, ok := variables[key]; !ok || !reflect.DeepEqual(prev, value) {
+			result.VariableChanges[key] = value
+		}
+	}
+	for _, e := range state.Errors {
+		if e.ProcessInstanceId == result.ProcessInstanceId || e.InstanceId == instance.Id {
+			result.Errors = append(result.Errors, e)
+		}
+	}
+	result.Undos = this.undoRec.get(result.ProcessInstanceId)
+
+	if !finished {
+		return result, fmt.Errorf("%w: %v", ErrTimeout, task.Id)
+	}
+	return result, nil
+}
+
+// undoRecorder wraps the handler and assigns Undo calls to the process instance of the last Do call;
+// camunda.Camunda handles tasks sequentially, so Undo always belongs to the last Do
+type undoRecorder struct {
+	mux       sync.Mutex
+	current   string
+	undoCalls map[string][]Undo
+}
+
+func (this *undoRecorder) middleware(handler camunda.Handler) camunda.Handler {
+	return &undoRecordingHandler{handler: handler, recorder: this}
+}
+
+func (this *undoRecorder) get(processInstanceId string) []Undo {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	return slices.Clone(this.undoCalls[processInstanceId])
+}
+
+type undoRecordingHandler struct {
+	handler  camunda.Handler
+	recorder *undoRecorder
+}
+
+func (this *undoRecordingHandler) Do(task model.CamundaExternalTask) (modules []model.Module, outputs map[string]interface{}, err error) {
+	this.recorder.mux.Lock()
+	this.recorder.current = task.ProcessInstanceId
+	this.recorder.mux.Unlock()
+	return this.handler.Do(task)
+}
+
+func (this *undoRecordingHandler) Undo(modules []model.Module, reason error) {
+	this.recorder.mux.Lock()
+	if this.recorder.undoCalls == nil {
+		this.recorder.undoCalls = map[string][]Undo{}
+	}
+	this.recorder.undoCalls[this.recorder.current] = append(this.recorder.undoCalls[this.recorder.current], Undo{Modules: modules, Reason: reason})
+	this.recorder.mux.Unlock()
+	this.handler.Undo(modules, reason)
+}
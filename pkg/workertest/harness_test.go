@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2026 InfAI (CC SES)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package workertest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/camunda"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/configuration"
+	"github.com/SENERGY-Platform/smart-service-module-worker-lib/pkg/model"
+)
+
+func TestHarness(t *testing.T) {
+	harness, err := Start(configuration.Config{LogLevel: "error"}, func(deps pkg.Dependencies) (camunda.Handler, error) {
+		return &handler{deps: deps}, nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer harness.Close()
+
+	t.Run("success", func(t *testing.T) {
+		result, err := harness.RunTask(map[string]interface{}{
+			"name":      "foo",
+			"prescript": `variables.write("pre", inputs.get("name") + variables.read("existing"));`,
+		}, map[string]interface{}{"existing": "bar"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !result.Completed || result.ProcessInstanceDeleted {
+			t.Error(result)
+			return
+		}
+		if !reflect.DeepEqual(result.Outputs, map[string]interface{}{"user": "user"}) {
+			t.Error(result.Outputs)
+		}
+		if !reflect.DeepEqual(result.VariableChanges, map[string]interface{}{"pre": "foobar"}) {
+			t.Error(result.VariableChanges)
+		}
+		if len(result.Modules) != 1 || result.Modules[0].ModuleData["name"] != "foo" || result.Modules[0].UserId != "user" {
+			t.Error(result.Modules)
+		}
+		if len(result.Errors) != 0 || len(result.Undos) != 0 {
+			t.Error(result.Errors, result.Undos)
+		}
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		result, err := harness.RunTask(map[string]interface{}{"fail": true}, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result.Completed || !result.ProcessInstanceDeleted {
+			t.Error(result)
+			return
+		}
+		if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "test error") {
+			t.Error(result.Errors)
+		}
+	})
+
+	t.Run("undo", func(t *testing.T) {
+		harness.Timeout = time.Second
+		defer func() {
+			harness.Timeout = 10 * time.Second
+		}()
+		result, err := harness.RunTask(map[string]interface{}{"unknownInstance": true}, nil)
+		if !errors.Is(err, ErrTimeout) {
+			t.Error(err)
+			return
+		}
+		if len(result.Undos) != 1 || len(result.Undos[0].Modules) != 1 {
+			t.Error(result.Undos)
+		}
+	})
+}
+
+func TestHarnessValidatesConfig(t *testing.T) {
+	harness, err := Start(configuration.Config{LogLevel: "error", CamundaFetchMaxTasks: -1}, func(deps pkg.Dependencies) (camunda.Handler, error) {
+		return &handler{deps: deps}, nil
+	})
+	validationErr := &configuration.ValidationError{}
+	if !errors.As(err, &validationErr) {
+		t.Error(err)
+	}
+	if err == nil {
+		harness.Close()
+	}
+}
+
+type handler struct {
+	deps pkg.Dependencies
+}
+
+func (this *handler) Do(task model.CamundaExternalTask) (modules []model.Module, outputs map[string]interface{}, err error) {
+	if task.Variables["fail"].Value == true {
+		return nil, nil, errors.New("test error")
+	}
+	userId, err := this.deps.SmartServiceRepo.GetInstanceUser(task.ProcessInstanceId)
+	if err != nil {
+		return nil, nil, err
+	}
+	processInstanceId := task.ProcessInstanceId
+	if task.Variables["unknownInstance"].Value == true {
+		processInstanceId = "unknown"
+	}
+	return []model.Module{{
+		Id:               task.Id + "-module",
+		ProcesInstanceId: processInstanceId,
+		SmartServiceModuleInit: model.SmartServiceModuleInit{
+			ModuleType: "test",
+			ModuleData: map[string]interface{}{"name": task.Variables["name"].Value},
+		},
+	}}, map[string]interface{}{"user": userId}, nil
+}
+
+func (this *handler) Undo(modules []model.Module, reason error) {}